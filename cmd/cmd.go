@@ -6,15 +6,19 @@ import (
 	"log/slog"
 	"os"
 	"runtime/debug"
+	"slices"
 
 	tea "charm.land/bubbletea/v2"
 	"gabe565.com/cli-of-life/internal/config"
 	"gabe565.com/cli-of-life/internal/game"
+	"gabe565.com/cli-of-life/internal/graphics"
 	"gabe565.com/cli-of-life/internal/pattern"
 	"gabe565.com/cli-of-life/internal/pprof"
 	"gabe565.com/cli-of-life/internal/quadtree"
+	"gabe565.com/cli-of-life/internal/theme"
 	"gabe565.com/utils/cobrax"
 	"gabe565.com/utils/must"
+	"github.com/charmbracelet/colorprofile"
 	"github.com/spf13/cobra"
 )
 
@@ -26,7 +30,7 @@ func New(opts ...cobrax.Option) *cobra.Command {
 		Args:  cobra.MaximumNArgs(1),
 
 		ValidArgsFunction: func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
-			return pattern.Extensions(), cobra.ShellCompDirectiveFilterFileExt
+			return slices.Concat(pattern.Extensions(), pattern.ArchiveExtensions()), cobra.ShellCompDirectiveFilterFileExt
 		},
 		DisableAutoGenTag: true,
 		SilenceErrors:     true,
@@ -65,14 +69,27 @@ func run(cmd *cobra.Command, args []string) error {
 		conf.Pattern = args[0]
 	}
 
+	conf.DetectASCII(cmd, os.Getenv)
+
+	if conf.Graphics != graphics.Auto {
+		if _, err := graphics.Parse(conf.Graphics); err != nil {
+			return err
+		}
+	}
+
+	if _, err := theme.Parse(conf.Theme); err != nil {
+		return err
+	}
+
 	if conf.CacheLimit > 0 {
 		quadtree.SetMaxCache(conf.CacheLimit)
 	}
 
-	program := tea.NewProgram(
-		game.New(conf),
-		tea.WithoutCatchPanics(),
-	)
+	programOpts := []tea.ProgramOption{tea.WithoutCatchPanics()}
+	if conf.ASCII {
+		programOpts = append(programOpts, tea.WithColorProfile(colorprofile.NoTTY))
+	}
+	program := tea.NewProgram(game.New(conf), programOpts...)
 
 	defer func() {
 		if err := recover(); err != nil {
@@ -0,0 +1,283 @@
+// Package apgcode converts patterns to and from the apgcodes used by Catagolue,
+// like "xq4_153" for the glider.
+package apgcode
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"image"
+	"iter"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gabe565.com/cli-of-life/internal/rule"
+)
+
+const (
+	// MaxPeriod is the longest period searched for when encoding.
+	MaxPeriod = 64
+	// MaxPopulation is the largest population which will be encoded.
+	MaxPopulation = 1024
+
+	digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+)
+
+var (
+	ErrInvalidCode     = errors.New("invalid apgcode")
+	ErrUnsupportedCode = errors.New("unsupported apgcode prefix")
+	ErrNotPeriodic     = errors.New("pattern is not periodic")
+	ErrTooLarge        = errors.New("pattern is too large")
+	ErrUnsupportedRule = errors.New("rules with B0 are not supported")
+	ErrEmptyPattern    = errors.New("pattern is empty")
+)
+
+// Decode returns the live cells of a still life ("xs"), oscillator ("xp"),
+// or spaceship ("xq") apgcode.
+func Decode(code string) ([]image.Point, error) {
+	prefix, wechsler, found := strings.Cut(code, "_")
+	if !found {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCode, code)
+	}
+	if len(prefix) < 3 || !strings.HasPrefix(prefix, "x") || !strings.ContainsRune("spq", rune(prefix[1])) {
+		return nil, fmt.Errorf("%w: %q", ErrUnsupportedCode, prefix)
+	}
+	if _, err := strconv.Atoi(prefix[2:]); err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCode, code)
+	}
+	return DecodeWechsler(wechsler)
+}
+
+// DecodeWechsler returns the live cells of a pattern in extended Wechsler
+// format. Each character is a column of a 5-row strip, with "w", "x" and "y"
+// abbreviating runs of empty columns and "z" starting the next strip.
+func DecodeWechsler(s string) ([]image.Point, error) {
+	var cells []image.Point
+	var x, y int
+	for i := 0; i < len(s); i++ {
+		switch b := s[i]; {
+		case b == 'w':
+			x += 2
+		case b == 'x':
+			x += 3
+		case b == 'y':
+			i++
+			if i == len(s) {
+				return nil, fmt.Errorf("%w: %q ends with y", ErrInvalidCode, s)
+			}
+			n := strings.IndexByte(digits, s[i])
+			if n == -1 {
+				return nil, fmt.Errorf("%w: %q after y in %q", ErrInvalidCode, string(s[i]), s)
+			}
+			x += 4 + n
+		case b == 'z':
+			x = 0
+			y += 5
+		default:
+			col := strings.IndexByte(digits[:32], b)
+			if col == -1 {
+				return nil, fmt.Errorf("%w: %q in %q", ErrInvalidCode, string(b), s)
+			}
+			for row := range 5 {
+				if col&(1<<row) != 0 {
+					cells = append(cells, image.Pt(x, y+row))
+				}
+			}
+			x++
+		}
+	}
+	return cells, nil
+}
+
+// Encode returns the canonical apgcode of a still life, oscillator, or
+// spaceship. The pattern is run until it repeats, and the smallest
+// representation across every phase and orientation is used.
+func Encode(cells iter.Seq[image.Point], r rule.Rule) (string, error) {
+	if slices.Contains(r.Born, 0) {
+		return "", ErrUnsupportedRule
+	}
+
+	var start []image.Point
+	for p := range cells {
+		if len(start) == MaxPopulation {
+			return "", fmt.Errorf("%w: population exceeds %d", ErrTooLarge, MaxPopulation)
+		}
+		start = append(start, p)
+	}
+	if len(start) == 0 {
+		return "", ErrEmptyPattern
+	}
+
+	origin, shape := normalize(start)
+	phases := [][]image.Point{shape}
+	cur := start
+	for period := 1; period <= MaxPeriod; period++ {
+		cur = step(cur, r)
+		if len(cur) == 0 {
+			return "", fmt.Errorf("%w: pattern dies", ErrNotPeriodic)
+		}
+		curOrigin, curShape := normalize(cur)
+		if !slices.Equal(curShape, shape) {
+			phases = append(phases, curShape)
+			continue
+		}
+
+		var prefix string
+		switch {
+		case curOrigin != origin:
+			prefix = "xq" + strconv.Itoa(period)
+		case period == 1:
+			prefix = "xs" + strconv.Itoa(len(start))
+		default:
+			prefix = "xp" + strconv.Itoa(period)
+		}
+		best := canonical(phases[0])
+		for _, phase := range phases[1:] {
+			best = smallest(best, canonical(phase))
+		}
+		return prefix + "_" + best, nil
+	}
+	return "", fmt.Errorf("%w: period exceeds %d", ErrNotPeriodic, MaxPeriod)
+}
+
+// normalize returns the top-left corner of the cells, and the cells moved so
+// that the corner is at the origin, sorted by row then column.
+func normalize(cells []image.Point) (image.Point, []image.Point) {
+	origin := cells[0]
+	for _, p := range cells[1:] {
+		origin.X = min(origin.X, p.X)
+		origin.Y = min(origin.Y, p.Y)
+	}
+	shape := make([]image.Point, 0, len(cells))
+	for _, p := range cells {
+		shape = append(shape, p.Sub(origin))
+	}
+	slices.SortFunc(shape, func(a, b image.Point) int {
+		return cmp.Or(cmp.Compare(a.Y, b.Y), cmp.Compare(a.X, b.X))
+	})
+	return origin, shape
+}
+
+// step runs a single generation.
+func step(cells []image.Point, r rule.Rule) []image.Point {
+	alive := make(map[image.Point]bool, len(cells))
+	neighbors := make(map[image.Point]int, len(cells)*4)
+	for _, p := range cells {
+		alive[p] = true
+		for y := -1; y <= 1; y++ {
+			for x := -1; x <= 1; x++ {
+				if x != 0 || y != 0 {
+					neighbors[p.Add(image.Pt(x, y))]++
+				}
+			}
+		}
+	}
+
+	next := make([]image.Point, 0, len(cells))
+	for p, n := range neighbors {
+		if alive[p] && slices.Contains(r.Survive, n) || !alive[p] && slices.Contains(r.Born, n) {
+			next = append(next, p)
+		}
+	}
+	return next
+}
+
+// orientation maps a column u and row v of the output onto a cell, relative to
+// the corner at (x, y).
+type orientation struct {
+	x, y, ux, uy, vx, vy int
+}
+
+// canonical returns the smallest Wechsler representation of a normalized shape
+// across all 8 rotations and reflections.
+func canonical(shape []image.Point) string {
+	set := make(map[image.Point]bool, len(shape))
+	var size image.Point
+	for _, p := range shape {
+		set[p] = true
+		size.X = max(size.X, p.X+1)
+		size.Y = max(size.Y, p.Y+1)
+	}
+	w, h := size.X-1, size.Y-1
+
+	var best string
+	for i, o := range [...]orientation{
+		{0, 0, 1, 0, 0, 1},
+		{w, 0, -1, 0, 0, 1},
+		{0, h, 1, 0, 0, -1},
+		{w, h, -1, 0, 0, -1},
+		{0, 0, 0, 1, 1, 0},
+		{w, 0, 0, 1, -1, 0},
+		{0, h, 0, -1, 1, 0},
+		{w, h, 0, -1, -1, 0},
+	} {
+		width, height := size.X, size.Y
+		if o.ux == 0 {
+			width, height = height, width
+		}
+		s := wechsler(set, o, width, height)
+		if i == 0 {
+			best = s
+		} else {
+			best = smallest(best, s)
+		}
+	}
+	return best
+}
+
+// smallest returns the shorter representation, or the lexicographically
+// smaller one if they are the same length.
+func smallest(a, b string) string {
+	if len(b) < len(a) || len(b) == len(a) && b < a {
+		return b
+	}
+	return a
+}
+
+// wechsler encodes the cells in extended Wechsler format, in the given orientation.
+func wechsler(set map[image.Point]bool, o orientation, width, height int) string {
+	var buf strings.Builder
+	for strip := 0; strip < height; strip += 5 {
+		if strip != 0 {
+			buf.WriteByte('z')
+		}
+		var zeros int
+		for u := range width {
+			var col int
+			for row := range min(5, height-strip) {
+				v := strip + row
+				if set[image.Pt(o.x+o.ux*u+o.vx*v, o.y+o.uy*u+o.vy*v)] {
+					col |= 1 << row
+				}
+			}
+			if col == 0 {
+				zeros++
+				continue
+			}
+			writeZeros(&buf, zeros)
+			zeros = 0
+			buf.WriteByte(digits[col])
+		}
+	}
+	return buf.String()
+}
+
+// writeZeros writes a run of empty columns.
+func writeZeros(buf *strings.Builder, n int) {
+	for ; n > 39; n -= 39 {
+		buf.WriteString("yz")
+	}
+	switch {
+	case n == 0:
+	case n == 1:
+		buf.WriteByte('0')
+	case n == 2:
+		buf.WriteByte('w')
+	case n == 3:
+		buf.WriteByte('x')
+	default:
+		buf.WriteByte('y')
+		buf.WriteByte(digits[n-4])
+	}
+}
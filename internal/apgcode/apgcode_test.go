@@ -0,0 +1,111 @@
+package apgcode
+
+import (
+	"image"
+	"slices"
+	"testing"
+
+	"gabe565.com/cli-of-life/internal/rule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name    string
+		code    string
+		want    []image.Point
+		wantErr error
+	}{
+		{"glider", "xq4_153", []image.Point{{0, 0}, {1, 0}, {1, 2}, {2, 0}, {2, 1}}, nil},
+		{"block", "xs4_33", []image.Point{{0, 0}, {0, 1}, {1, 0}, {1, 1}}, nil},
+		{"strips", "xp2_1z1", []image.Point{{0, 0}, {0, 5}}, nil},
+		{"zeros", "xs3_10w1y01", []image.Point{{0, 0}, {4, 0}, {9, 0}}, nil},
+		{"no separator", "xs4", nil, ErrInvalidCode},
+		{"unsupported prefix", "yl144_1_16_afb5f3db909e60548f086e22ee3353ac", nil, ErrUnsupportedCode},
+		{"invalid period", "xpa_7", nil, ErrInvalidCode},
+		{"invalid character", "xs4_3!", nil, ErrInvalidCode},
+		{"trailing y", "xs4_3y", nil, ErrInvalidCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.code)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, got)
+		})
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+	}{
+		{"block", "xs4_33"},
+		{"beehive", "xs6_696"},
+		{"boat", "xs5_253"},
+		{"blinker", "xp2_7"},
+		{"glider", "xq4_153"},
+		{"lwss", "xq4_6frc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cells, err := Decode(tt.code)
+			require.NoError(t, err)
+			got, err := Encode(slices.Values(cells), rule.GameOfLife())
+			require.NoError(t, err)
+			assert.Equal(t, tt.code, got)
+		})
+	}
+
+	t.Run("orientation", func(t *testing.T) {
+		// A glider moving in a different direction, in a different phase.
+		cells := []image.Point{{-10, 7}, {-9, 8}, {-8, 8}, {-10, 9}, {-9, 9}}
+		got, err := Encode(slices.Values(cells), rule.GameOfLife())
+		require.NoError(t, err)
+		assert.Equal(t, "xq4_153", got)
+	})
+
+	t.Run("dies", func(t *testing.T) {
+		_, err := Encode(slices.Values([]image.Point{{0, 0}}), rule.GameOfLife())
+		require.ErrorIs(t, err, ErrNotPeriodic)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		_, err := Encode(slices.Values([]image.Point(nil)), rule.GameOfLife())
+		require.ErrorIs(t, err, ErrEmptyPattern)
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		cells := make([]image.Point, 0, MaxPopulation+1)
+		for i := range MaxPopulation + 1 {
+			cells = append(cells, image.Pt(i*3, 0))
+		}
+		_, err := Encode(slices.Values(cells), rule.GameOfLife())
+		require.ErrorIs(t, err, ErrTooLarge)
+	})
+
+	t.Run("B0", func(t *testing.T) {
+		_, err := Encode(slices.Values([]image.Point{{0, 0}}), rule.Rule{Born: []int{0, 3}})
+		require.ErrorIs(t, err, ErrUnsupportedRule)
+	})
+}
+
+func FuzzDecode(f *testing.F) {
+	for _, code := range []string{"xq4_153", "xs4_33", "xp2_7", "xq4_6frc", "xs3_10w1y01", "xp2_1z1"} {
+		f.Add(code)
+	}
+
+	f.Fuzz(func(t *testing.T, code string) {
+		cells, err := Decode(code)
+		if err != nil || len(cells) == 0 || len(cells) > 64 {
+			return
+		}
+		// Encoding may fail if the decoded cells are not periodic, but must not panic.
+		_, _ = Encode(slices.Values(cells), rule.GameOfLife())
+	})
+}
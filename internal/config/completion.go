@@ -3,7 +3,9 @@ package config
 import (
 	"errors"
 
+	"gabe565.com/cli-of-life/internal/graphics"
 	"gabe565.com/cli-of-life/internal/rule"
+	"gabe565.com/cli-of-life/internal/theme"
 	"github.com/spf13/cobra"
 )
 
@@ -19,5 +21,20 @@ func RegisterCompletion(cmd *cobra.Command) error {
 		),
 		cmd.RegisterFlagCompletionFunc(PlayFlag, cobra.NoFileCompletions),
 		cmd.RegisterFlagCompletionFunc(CacheLimitFlag, cobra.NoFileCompletions),
+		cmd.RegisterFlagCompletionFunc(BirthChanceFlag, cobra.NoFileCompletions),
+		cmd.RegisterFlagCompletionFunc(SurviveChanceFlag, cobra.NoFileCompletions),
+		cmd.RegisterFlagCompletionFunc(NoiseFlag, cobra.NoFileCompletions),
+		cmd.RegisterFlagCompletionFunc(SeedFlag, cobra.NoFileCompletions),
+		cmd.RegisterFlagCompletionFunc(GraphicsFlag,
+			func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+				return graphics.Names(), cobra.ShellCompDirectiveNoFileComp
+			},
+		),
+		cmd.RegisterFlagCompletionFunc(ASCIIFlag, cobra.NoFileCompletions),
+		cmd.RegisterFlagCompletionFunc(ThemeFlag,
+			func(_ *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+				return theme.Names(), cobra.ShellCompDirectiveNoFileComp
+			},
+		),
 	)
 }
@@ -1,7 +1,11 @@
 package config
 
 import (
+	"gabe565.com/cli-of-life/internal/graphics"
+	"gabe565.com/cli-of-life/internal/quadtree"
 	"gabe565.com/cli-of-life/internal/rule"
+	"gabe565.com/cli-of-life/internal/theme"
+	"github.com/spf13/cobra"
 )
 
 type Config struct {
@@ -12,6 +16,20 @@ type Config struct {
 	Play          bool
 	CacheLimit    int
 
+	MaxPatternBytes int64
+	MaxPatternSize  int
+	MaxPatternCells int
+	LenientHeaders  bool
+
+	BirthChance   float64
+	SurviveChance float64
+	Noise         float64
+	Seed          uint64
+
+	Graphics string
+	Theme    string
+	ASCII    bool
+
 	Completion string
 }
 
@@ -20,5 +38,34 @@ func New() *Config {
 		PatternFormat: "auto",
 		RuleString:    rule.GameOfLife().String(),
 		CacheLimit:    10_000_000,
+
+		MaxPatternBytes: 256 << 20,
+		MaxPatternSize:  1 << 24,
+		MaxPatternCells: 50_000_000,
+
+		BirthChance:   1,
+		SurviveChance: 1,
+		Graphics:      graphics.Auto,
+		Theme:         theme.Default,
+	}
+}
+
+// DetectASCII enables ASCII mode if NO_COLOR is set or the terminal is dumb.
+// An explicit --ascii flag, including --ascii=false, takes precedence.
+func (c *Config) DetectASCII(cmd *cobra.Command, getenv func(string) string) {
+	if cmd.Flags().Changed(ASCIIFlag) {
+		return
+	}
+	if getenv("NO_COLOR") != "" || getenv("TERM") == "dumb" {
+		c.ASCII = true
+	}
+}
+
+func (c *Config) Stochastic() quadtree.StochasticOptions {
+	return quadtree.StochasticOptions{
+		BirthChance:   c.BirthChance,
+		SurviveChance: c.SurviveChance,
+		Noise:         c.Noise,
+		Seed:          c.Seed,
 	}
 }
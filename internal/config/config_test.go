@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_DetectASCII(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		want bool
+	}{
+		{"default", nil, nil, false},
+		{"NO_COLOR", nil, map[string]string{"NO_COLOR": "1"}, true},
+		{"dumb terminal", nil, map[string]string{"TERM": "dumb"}, true},
+		{"flag", []string{"--ascii"}, nil, true},
+		{"flag disabled with NO_COLOR", []string{"--ascii=false"}, map[string]string{"NO_COLOR": "1"}, false},
+		{"flag disabled with dumb terminal", []string{"--ascii=false"}, map[string]string{"TERM": "dumb"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := New()
+			cmd := &cobra.Command{}
+			conf.RegisterFlags(cmd)
+			require.NoError(t, cmd.ParseFlags(tt.args))
+
+			conf.DetectASCII(cmd, func(key string) string {
+				return tt.env[key]
+			})
+			assert.Equal(t, tt.want, conf.ASCII)
+		})
+	}
+}
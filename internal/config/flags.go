@@ -3,7 +3,10 @@ package config
 import (
 	"bytes"
 	"log/slog"
+	"strings"
 
+	"gabe565.com/cli-of-life/internal/graphics"
+	"gabe565.com/cli-of-life/internal/theme"
 	"gabe565.com/utils/must"
 	"github.com/spf13/cobra"
 )
@@ -13,6 +16,20 @@ const (
 	PlayFlag       = "play"
 	CacheLimitFlag = "cache-limit"
 
+	MaxPatternBytesFlag = "max-pattern-bytes"
+	MaxPatternSizeFlag  = "max-pattern-size"
+	MaxPatternCellsFlag = "max-pattern-cells"
+	LenientHeadersFlag  = "lenient-headers"
+
+	BirthChanceFlag   = "birth-chance"
+	SurviveChanceFlag = "survive-chance"
+	NoiseFlag         = "noise"
+	SeedFlag          = "seed"
+
+	GraphicsFlag = "graphics"
+	ThemeFlag    = "theme"
+	ASCIIFlag    = "ascii"
+
 	// Deprecated: Pass file as positional argument instead.
 	FileFlag = "file"
 	// Deprecated: Pass URL as positional argument instead.
@@ -29,6 +46,41 @@ func (c *Config) RegisterFlags(cmd *cobra.Command) {
 		"Maximum number of entries to keep cached. Higher values will use more memory, but less CPU.",
 	)
 
+	fs.Int64Var(&c.MaxPatternBytes, MaxPatternBytesFlag, c.MaxPatternBytes,
+		"Maximum size in bytes of a pattern file to load, after decompression. If 0, there is no limit.",
+	)
+	fs.IntVar(&c.MaxPatternSize, MaxPatternSizeFlag, c.MaxPatternSize,
+		"Maximum width or height of a pattern to load. If 0, there is no limit.",
+	)
+	fs.IntVar(&c.MaxPatternCells, MaxPatternCellsFlag, c.MaxPatternCells,
+		"Maximum number of live cells in a pattern to load. If 0, there is no limit.",
+	)
+	fs.BoolVar(&c.LenientHeaders, LenientHeadersFlag, c.LenientHeaders,
+		"Load RLE patterns whose cells do not fit in the header size, logging a warning instead of failing.",
+	)
+
+	fs.Float64Var(&c.BirthChance, BirthChanceFlag, c.BirthChance,
+		"Probability that a cell is born when the rule allows it. Values below 1 disable Hashlife.",
+	)
+	fs.Float64Var(&c.SurviveChance, SurviveChanceFlag, c.SurviveChance,
+		"Probability that a cell survives when the rule allows it. Values below 1 disable Hashlife.",
+	)
+	fs.Float64Var(&c.Noise, NoiseFlag, c.Noise,
+		"Probability that each cell in the pattern bounds is flipped every generation. Values above 0 disable Hashlife.",
+	)
+	fs.Uint64Var(&c.Seed, SeedFlag, c.Seed, "Random seed for stochastic rules. If 0, a random seed is used.")
+
+	fs.StringVar(&c.Graphics, GraphicsFlag, c.Graphics,
+		"Pixel graphics protocol. One of: "+strings.Join(graphics.Names(), ", "),
+	)
+	fs.StringVar(&c.Theme, ThemeFlag, c.Theme,
+		"Color theme. One of: "+strings.Join(theme.Names(), ", ")+", or a comma-separated list of hex or ANSI 256 colors",
+	)
+
+	fs.BoolVar(&c.ASCII, ASCIIFlag, c.ASCII,
+		"Render using only ASCII characters and no colors. Enabled automatically if NO_COLOR is set or TERM is dumb.",
+	)
+
 	fs.StringVarP(&c.Pattern, FileFlag, "f", c.Pattern, "Load a pattern file")
 	fs.StringVar(&c.Pattern, URLFlag, c.Pattern, "Load a pattern URL")
 	must.Must(fs.MarkDeprecated(FileFlag, "pass file as positional argument instead."))
@@ -9,6 +9,8 @@ type ViewMsg uint8
 const (
 	Menu ViewMsg = iota
 	Conway
+	// Save shows the menu with the save pattern form open.
+	Save
 )
 
 func ChangeView(view ViewMsg) tea.Cmd {
@@ -3,6 +3,7 @@ package buttons
 import (
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
+	"gabe565.com/cli-of-life/internal/theme"
 	zone "github.com/lrstanley/bubblezone/v2"
 )
 
@@ -24,14 +25,15 @@ func New(names ...string) *Buttons {
 	b := &Buttons{
 		List:     btns,
 		Position: lipgloss.Center,
+		theme:    theme.New(),
+		dark:     true,
 	}
-	b.setStyles(true)
+	b.setStyles()
 	return b
 }
 
-func (b *Buttons) setStyles(dark bool) {
-	lightDark := lipgloss.LightDark(dark)
-	bgColor := lightDark(lipgloss.Color("#DDDADA"), lipgloss.Color("#4A4A4A"))
+func (b *Buttons) setStyles() {
+	bgColor, selectedBgColor := b.theme.Button(b.dark)
 
 	btnStyle := lipgloss.NewStyle().
 		Border(lipgloss.InnerHalfBlockBorder()).
@@ -39,8 +41,17 @@ func (b *Buttons) setStyles(dark bool) {
 		Padding(0, 3).
 		Background(bgColor).
 		Width(20)
-
-	selectedBgColor := lightDark(lipgloss.Color("#aaf"), lipgloss.Color("#4A4ABB"))
+	if b.ascii {
+		btnStyle = lipgloss.NewStyle().
+			Border(lipgloss.ASCIIBorder()).
+			Padding(0, 3).
+			Width(20)
+		b.styles = styles{
+			button:   btnStyle,
+			selected: btnStyle.PaddingLeft(1),
+		}
+		return
+	}
 
 	b.styles = styles{
 		button: btnStyle,
@@ -52,7 +63,19 @@ func (b *Buttons) setStyles(dark bool) {
 }
 
 func (b *Buttons) SetDark(dark bool) {
-	b.setStyles(dark)
+	b.dark = dark
+	b.setStyles()
+}
+
+// SetASCII draws buttons with ASCII borders and no colors.
+func (b *Buttons) SetASCII(enabled bool) {
+	b.ascii = enabled
+	b.setStyles()
+}
+
+func (b *Buttons) SetTheme(t theme.Theme) {
+	b.theme = t
+	b.setStyles()
 }
 
 type styles struct {
@@ -62,6 +85,9 @@ type styles struct {
 
 type Buttons struct {
 	styles styles
+	theme  theme.Theme
+	dark   bool
+	ascii  bool
 
 	Position lipgloss.Position
 	List     []*Button
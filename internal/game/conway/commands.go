@@ -7,9 +7,12 @@ import (
 	tea "charm.land/bubbletea/v2"
 )
 
-type tickMsg struct{}
+type frameMsg struct{}
 
-func Tick(ctx context.Context, wait time.Duration) tea.Cmd {
+// frameInterval is how often the universe is redrawn while playing.
+const frameInterval = time.Second / 30
+
+func Frame(ctx context.Context, wait time.Duration) tea.Cmd {
 	return func() tea.Msg {
 		if ctx == nil {
 			return nil
@@ -18,7 +21,18 @@ func Tick(ctx context.Context, wait time.Duration) tea.Cmd {
 		case <-ctx.Done():
 			return nil
 		case <-time.After(wait):
-			return tickMsg{}
+			return frameMsg{}
 		}
 	}
 }
+
+type stepDoneMsg struct {
+	state *stepState
+	err   error
+}
+
+func stepCmd(ctx context.Context, state *stepState, fn func(ctx context.Context) error) tea.Cmd {
+	return func() tea.Msg {
+		return stepDoneMsg{state: state, err: fn(ctx)}
+	}
+}
@@ -3,6 +3,7 @@ package conway
 import (
 	"bytes"
 	"context"
+	"errors"
 	"image"
 	"strconv"
 	"strings"
@@ -10,13 +11,20 @@ import (
 
 	"charm.land/bubbles/v2/help"
 	"charm.land/bubbles/v2/key"
+	"charm.land/bubbles/v2/progress"
+	"charm.land/bubbles/v2/spinner"
 	tea "charm.land/bubbletea/v2"
 	"charm.land/lipgloss/v2"
 	"charm.land/lipgloss/v2/table"
 	"gabe565.com/cli-of-life/internal/config"
 	"gabe565.com/cli-of-life/internal/game/commands"
+	"gabe565.com/cli-of-life/internal/game/util"
+	"gabe565.com/cli-of-life/internal/graphics"
+	"gabe565.com/cli-of-life/internal/metrics"
 	"gabe565.com/cli-of-life/internal/pattern"
 	"gabe565.com/cli-of-life/internal/quadtree"
+	"gabe565.com/cli-of-life/internal/theme"
+	uv "github.com/charmbracelet/ultraviolet"
 )
 
 type Mode uint8
@@ -29,24 +37,43 @@ const (
 
 func NewConway(conf *config.Config) *Conway {
 	conway := &Conway{
+		config:   conf,
+		metrics:  metrics.New(),
 		keymap:   newKeymap(),
 		help:     help.New(),
+		spinner:  spinner.New(spinner.WithSpinner(spinner.Dot)),
+		progress: progress.New(progress.WithDefaultBlend(), progress.WithoutPercentage()),
 		speed:    5,
 		smartVal: -1,
+		scale:    1,
+		dark:     true,
 	}
 
 	if conf.Play {
 		conway.ResumeOnFocus = true
 	}
+	if conf.ASCII {
+		quadtree.SetASCII(true)
+		util.ASCIIHelp(&conway.help)
+		conway.spinner.Spinner = spinner.Line
+		conway.progress = progress.New(progress.WithFillCharacters('#', '.'), progress.WithoutPercentage())
+		conway.keymap.braille.SetEnabled(false)
+	}
+	conway.initGraphics()
 
 	return conway
 }
 
 type Conway struct {
+	config        *config.Config
 	viewSize      tea.WindowSizeMsg
 	gameSize      image.Point
 	view          image.Point
+	offset        quadtree.BigPoint
 	level         uint8
+	scale         int
+	grid          bool
+	braille       bool
 	Pattern       *pattern.Pattern
 	ctx           context.Context
 	cancel        context.CancelFunc
@@ -58,41 +85,91 @@ type Conway struct {
 	speed         int
 	viewBuf       bytes.Buffer
 	debug         bool
+	err           error
+	spinner       spinner.Model
+	spinning      bool
+	progress      progress.Model
+	stepping      *stepState
+	worker        *worker
+	metrics       *metrics.Recorder
+
+	dark           bool
+	visible        bool
+	graphics       graphics.Protocol
+	detectGraphics bool
+	cellSize       image.Point
+
+	apgRoot *quadtree.Node
+	apgCode string
 }
 
 func (c *Conway) Init() tea.Cmd {
 	if c.ctx != nil {
-		return Tick(c.ctx, speeds[c.speed])
+		return Frame(c.ctx, frameInterval)
 	}
 	return nil
 }
 
 func (c *Conway) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	model, cmd := c.update(msg)
+	if c.graphicsEnabled() {
+		switch msg.(type) {
+		case frameMsg, stepDoneMsg, tea.KeyPressMsg, tea.MouseMsg, tea.WindowSizeMsg, commands.ViewMsg:
+			cmd = tea.Batch(cmd, c.drawGraphics())
+		}
+	}
+	return model, cmd
+}
+
+func (c *Conway) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
-	case tickMsg:
-		steps := uint64(1)
-		if speeds[c.speed] < time.Second/240 {
-			steps += uint64(time.Second / 240 / speeds[c.speed])
+	case uv.PrimaryDeviceAttributesEvent, uv.CellSizeEvent:
+		c.updateGraphics(msg)
+	case frameMsg:
+		c.followOffset()
+		if c.worker != nil {
+			if err := c.worker.Err(); err != nil {
+				c.Pause()
+				c.err = err
+				return c, nil
+			}
 		}
-		c.Pattern.Step(steps)
 		if c.ctx != nil {
-			return c, Tick(c.ctx, speeds[c.speed])
+			return c, Frame(c.ctx, frameInterval)
+		}
+	case stepDoneMsg:
+		if msg.state != c.stepping {
+			return c, nil
 		}
+		c.stepping = nil
+		c.followOffset()
+		if msg.err != nil && !errors.Is(msg.err, context.Canceled) && !errors.Is(msg.err, quadtree.ErrReset) {
+			c.err = msg.err
+		}
+	case spinner.TickMsg:
+		if c.stepping == nil {
+			c.spinning = false
+			return c, nil
+		}
+		var cmd tea.Cmd
+		c.spinner, cmd = c.spinner.Update(msg)
+		return c, cmd
 	case tea.WindowSizeMsg:
 		if c.viewSize.Width == 0 && c.viewSize.Height == 0 && c.Pattern != nil {
 			defer c.center()
 		}
 		c.viewSize = msg
-		c.gameSize.X, c.gameSize.Y = (msg.Width/2)<<c.level, (msg.Height-1)<<c.level
+		c.resize()
 		c.viewBuf.Reset()
 		c.viewBuf.Grow(c.viewSize.Width * c.viewSize.Height)
 	case tea.MouseMsg:
 		mouse := msg.Mouse()
 		switch msg.(type) {
 		case tea.MouseClickMsg, tea.MouseMotionMsg:
-			if mouse.Button == tea.MouseLeft && c.level == 0 {
-				mouse.X = mouse.X/2 + c.view.X
-				mouse.Y += c.view.Y
+			if mouse.Button == tea.MouseLeft && c.level == 0 && !c.braille && c.stepping == nil {
+				mouse.X = mouse.X/(2*c.scale) + c.view.X
+				mouse.Y = mouse.Y/c.scale + c.view.Y
+				var err error
 				switch c.mode {
 				case ModeSmart:
 					if c.smartVal == -1 {
@@ -103,11 +180,14 @@ func (c *Conway) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 							c.smartVal = 1
 						}
 					}
-					c.Pattern.Tree.Set(image.Pt(mouse.X, mouse.Y), c.smartVal)
+					err = c.Pattern.Tree.Set(image.Pt(mouse.X, mouse.Y), c.smartVal)
 				case ModePlace:
-					c.Pattern.Tree.Set(image.Pt(mouse.X, mouse.Y), 1)
+					err = c.Pattern.Tree.Set(image.Pt(mouse.X, mouse.Y), 1)
 				case ModeErase:
-					c.Pattern.Tree.Set(image.Pt(mouse.X, mouse.Y), 0)
+					err = c.Pattern.Tree.Set(image.Pt(mouse.X, mouse.Y), 0)
+				}
+				if err != nil {
+					c.err = err
 				}
 			}
 		case tea.MouseWheelMsg:
@@ -125,6 +205,7 @@ func (c *Conway) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			c.smartVal = -1
 		}
 	case tea.KeyPressMsg:
+		c.err = nil
 		switch {
 		case key.Matches(msg, c.keymap.playPause):
 			if c.ctx == nil {
@@ -132,10 +213,13 @@ func (c *Conway) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			c.Pause()
 		case key.Matches(msg, c.keymap.tick):
-			if c.ctx == nil {
-				return c, func() tea.Msg {
-					return tickMsg{}
-				}
+			if c.ctx == nil && c.stepping == nil {
+				return c, c.startStep(1, false)
+			}
+		case key.Matches(msg, c.keymap.jump):
+			if c.stepping == nil {
+				c.Pause()
+				return c, c.startStep(jumpSize, true)
 			}
 		case key.Matches(msg, c.keymap.mode):
 			switch c.mode {
@@ -158,19 +242,38 @@ func (c *Conway) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, c.keymap.moveRight):
 			c.Scroll(DirRight, 2)
 		case key.Matches(msg, c.keymap.zoomIn):
-			if c.level > 0 {
+			switch {
+			case c.level > 0:
 				center := c.view.Add(c.gameSize.Div(2))
 				c.level--
 				c.gameSize = c.gameSize.Div(2)
 				c.view = center.Sub(c.gameSize.Div(2))
+			case !c.braille && c.scale < maxScale:
+				center := c.view.Add(c.gameSize.Div(2))
+				c.scale++
+				c.resize()
+				c.view = center.Sub(c.gameSize.Div(2))
 			}
 		case key.Matches(msg, c.keymap.zoomOut):
-			if c.level < c.Pattern.Tree.Level()-2 {
+			if c.scale > 1 {
+				center := c.view.Add(c.gameSize.Div(2))
+				c.scale--
+				c.resize()
+				c.view = center.Sub(c.gameSize.Div(2))
+			} else if c.level < c.Pattern.Tree.Level()-2 {
 				center := c.view.Add(c.gameSize.Div(2))
 				c.level++
 				c.gameSize = c.gameSize.Mul(2)
 				c.view = center.Sub(c.gameSize.Div(2))
 			}
+		case key.Matches(msg, c.keymap.braille):
+			center := c.view.Add(c.gameSize.Div(2))
+			c.braille = !c.braille
+			c.scale = 1
+			c.resize()
+			c.view = center.Sub(c.gameSize.Div(2))
+		case key.Matches(msg, c.keymap.grid):
+			c.grid = !c.grid
 		case key.Matches(msg, c.keymap.speedUp):
 			if c.speed < len(speeds)-1 {
 				c.speed++
@@ -193,17 +296,24 @@ func (c *Conway) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			c.Reset()
 		case key.Matches(msg, c.keymap.menu):
 			return c, commands.ChangeView(commands.Menu)
+		case key.Matches(msg, c.keymap.save):
+			return c, commands.ChangeView(commands.Save)
 		case key.Matches(msg, c.keymap.quit):
 			c.Pause()
 			return c, tea.Quit
 		case key.Matches(msg, c.keymap.debug):
 			c.debug = !c.debug
+			if c.debug {
+				return c, c.clearGraphics()
+			}
 		}
 	case commands.ViewMsg:
 		switch msg {
 		case commands.Conway:
+			c.visible = true
 			if c.Pattern == nil {
 				c.Pattern = pattern.Default()
+				c.Pattern.ApplyConfig(c.config)
 			}
 			if c.ResumeOnFocus {
 				c.ResumeOnFocus = false
@@ -214,6 +324,11 @@ func (c *Conway) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				c.ResumeOnFocus = true
 				c.Pause()
 			}
+			c.cancelStep()
+			if c.visible {
+				c.visible = false
+				return c, c.clearGraphics()
+			}
 		}
 	}
 	return c, nil
@@ -228,22 +343,46 @@ func (c *Conway) View() tea.View {
 			c.RenderStats(),
 		)
 		c.viewBuf.WriteString(stats)
+	} else if c.graphics != graphics.None {
+		c.blankView()
 	} else if c.gameSize.X != 0 && c.gameSize.Y != 0 {
-		c.Pattern.Tree.Render(&c.viewBuf, image.Rectangle{Min: c.view, Max: c.view.Add(c.gameSize)}, c.level)
+		start := time.Now()
+		rect := image.Rectangle{Min: c.view, Max: c.view.Add(c.gameSize)}
+		switch {
+		case c.braille:
+			c.Pattern.Tree.RenderBraille(&c.viewBuf, rect, c.level)
+		case c.scale > 1:
+			c.Pattern.Tree.RenderZoomed(&c.viewBuf, rect, c.scale, c.grid)
+		default:
+			c.Pattern.Tree.Render(&c.viewBuf, rect, c.level)
+		}
+		c.metrics.RecordRender(time.Since(start))
 		if c.viewSize.Height < c.gameSize.Y {
 			c.viewBuf.WriteString(strings.Repeat("\n", c.viewSize.Height-lipgloss.Height(c.viewBuf.String())))
 		}
 	}
+	switch {
+	case c.err != nil:
+		return tea.NewView(c.viewBuf.String() + errorStyle.Render(c.err.Error()))
+	case c.stepping != nil && c.stepping.visible():
+		return tea.NewView(c.viewBuf.String() + c.stepView())
+	}
 	return tea.NewView(c.viewBuf.String() + c.help.ShortHelpView(c.keymap.ShortHelp()))
 }
 
 func (c *Conway) SetDark(dark bool) {
+	c.dark = dark
 	c.help.Styles = help.DefaultStyles(dark)
 	quadtree.SetDarkBackground(dark)
 }
 
+func (c *Conway) SetTheme(t theme.Theme) {
+	quadtree.SetTheme(t)
+}
+
 func (c *Conway) RenderStats() string {
 	stats := c.Pattern.Tree.Stats()
+	m := c.Metrics()
 	t := table.New().
 		StyleFunc(func(_, col int) lipgloss.Style {
 			s := lipgloss.NewStyle().Padding(0, 1)
@@ -256,32 +395,88 @@ func (c *Conway) RenderStats() string {
 			return s
 		}).
 		Row("Steps", strconv.Itoa(stats.Steps)).
-		Row("Generation", strconv.FormatInt(int64(stats.Generation), 10)). //nolint:gosec
+		Row("Target Speed", strconv.Itoa(int(time.Second/speeds[c.speed]))+" gen/s").
+		Row("Measured Speed", strconv.FormatFloat(m.GenerationsPerSecond, 'f', 0, 64)+" gen/s").
+		Row("Generation", stats.Generation.String()).
 		Row("Level", strconv.Itoa(stats.Level)).
 		Row("Population", strconv.Itoa(stats.Population)).
+		Row("apgcode", c.apgcode()).
 		Row("Cache Size", strconv.Itoa(stats.CacheSize)).
 		Row("Cache Hit", strconv.FormatInt(int64(stats.CacheHit), 10)).   //nolint:gosec
 		Row("Cache Miss", strconv.FormatInt(int64(stats.CacheMiss), 10)). //nolint:gosec
-		Row("Cache Ratio", strconv.FormatFloat(float64(stats.CacheRatio()), 'f', 3, 32))
+		Row("Cache Ratio", strconv.FormatFloat(float64(stats.CacheRatio()), 'f', 3, 32)).
+		Row("Step Time", formatMillis(m.StepTime)).
+		Row("Render Time", formatMillis(m.RenderTime)).
+		Row("Allocs/Step", strconv.FormatFloat(m.AllocsPerStep, 'f', 1, 64)).
+		Row("Heap In Use", strconv.FormatFloat(float64(m.HeapInUse)/(1<<20), 'f', 1, 64)+" MiB").
+		Row("GC Pauses", strconv.Itoa(m.GCPauses)+" / "+formatMillis(m.GCPauseTime)).
+		Row("GC Cycles", strconv.FormatUint(m.GCCycles, 10))
+	if c.config.ASCII {
+		t = t.Border(lipgloss.ASCIIBorder())
+	}
 	return lipgloss.JoinVertical(lipgloss.Center,
 		lipgloss.NewStyle().Bold(true).Render("Stats"),
 		t.Render(),
 	)
 }
 
+// apgcode returns the apgcode of the current generation, or "-" if the
+// pattern is not a recognizable object. Encoding is too slow to run every
+// frame, so it is only computed while paused and cached until the pattern
+// changes.
+func (c *Conway) apgcode() string {
+	if c.worker != nil || c.stepping != nil {
+		return "(running)"
+	}
+	if root := c.Pattern.Tree.Snapshot().Root(); root != c.apgRoot {
+		c.apgRoot = root
+		c.apgCode = "-"
+		if code, err := c.Pattern.APGCode(); err == nil {
+			c.apgCode = code
+		}
+	}
+	return c.apgCode
+}
+
+func formatMillis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64) + " ms"
+}
+
 func (c *Conway) center() {
-	size := c.Pattern.Tree.FilledCoords().Size()
-	c.view.X = size.X/2 - c.gameSize.X/2
-	c.view.Y = size.Y/2 - c.gameSize.Y/2
+	snapshot := c.Pattern.Tree.Snapshot()
+	bounds := snapshot.Root().FilledCoords()
+	c.view = bounds.Min.Add(bounds.Size().Div(2)).Sub(c.gameSize.Div(2))
+	c.offset = snapshot.Offset()
+}
+
+// followOffset moves the view along with the cells when the tree is
+// recentered, so the same part of the universe stays on screen.
+func (c *Conway) followOffset() {
+	if c.Pattern == nil {
+		return
+	}
+	offset := c.Pattern.Tree.Offset()
+	if offset.Eq(c.offset) {
+		return
+	}
+	if delta, ok := offset.Sub(c.offset).Point(); ok {
+		c.view = c.view.Sub(delta)
+		c.offset = offset
+	} else {
+		c.center()
+	}
 }
 
 func (c *Conway) Play() tea.Cmd {
 	if c.cancel != nil {
 		c.cancel()
 	}
+	c.cancelStep()
+	c.stopWorker()
 	c.keymap.playPause.SetHelp(c.keymap.playPause.Help().Key, "pause")
+	c.worker = startWorker(c.Pattern, speeds[c.speed], c.metrics)
 	c.ctx, c.cancel = context.WithCancel(context.Background())
-	return Tick(c.ctx, speeds[c.speed])
+	return Frame(c.ctx, frameInterval)
 }
 
 func (c *Conway) Pause() {
@@ -290,17 +485,33 @@ func (c *Conway) Pause() {
 		c.cancel()
 	}
 	c.ctx, c.cancel = nil, nil
+	c.stopWorker()
+}
+
+func (c *Conway) stopWorker() {
+	if c.worker != nil {
+		c.worker.Stop()
+		c.worker = nil
+	}
+}
+
+// Metrics returns rolling performance measurements.
+func (c *Conway) Metrics() metrics.Stats {
+	return c.metrics.Stats()
 }
 
 func (c *Conway) Clear() {
 	c.ResumeOnFocus = false
+	c.cancelStep()
 	quadtree.ResetCache()
 	c.Pattern = pattern.Default()
+	c.Pattern.ApplyConfig(c.config)
 	c.ResetView()
 }
 
 func (c *Conway) Reset() {
 	c.ResumeOnFocus = false
+	c.cancelStep()
 	quadtree.ResetCache()
 	c.Pattern.Tree.Reset()
 	c.ResetView()
@@ -309,11 +520,30 @@ func (c *Conway) Reset() {
 func (c *Conway) ResetView() {
 	if c.Pattern != nil {
 		c.level = 0
-		c.gameSize.X, c.gameSize.Y = c.viewSize.Width/2, c.viewSize.Height-1
+		c.scale = 1
+		c.resize()
 		c.center()
 	}
 }
 
+// maxScale is the largest magnification, where each cell is drawn as a block
+// of 2*maxScale columns by maxScale rows.
+const maxScale = 8
+
+// resize updates the number of cells shown on screen. Half-block mode uses two
+// columns per cell, or more when magnified, while braille mode fits 2x4 cells
+// in each character.
+func (c *Conway) resize() {
+	width, height := c.viewSize.Width, c.viewSize.Height-1
+	if c.braille {
+		c.gameSize.X, c.gameSize.Y = width*2, height*4
+	} else {
+		c.gameSize.X, c.gameSize.Y = width/(2*c.scale), height/c.scale
+	}
+	c.gameSize.X <<= c.level
+	c.gameSize.Y <<= c.level
+}
+
 type Direction uint8
 
 const (
@@ -1,14 +1,133 @@
 package conway
 
 import (
+	"image"
+	"math/big"
 	"testing"
 	"time"
 
+	tea "charm.land/bubbletea/v2"
 	"gabe565.com/cli-of-life/internal/config"
+	"gabe565.com/cli-of-life/internal/game/commands"
+	"gabe565.com/cli-of-life/internal/graphics"
+	"gabe565.com/cli-of-life/internal/pattern"
+	"gabe565.com/cli-of-life/internal/quadtree"
+	uv "github.com/charmbracelet/ultraviolet"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDefaultSpeed(t *testing.T) {
 	conway := NewConway(config.New())
 	assert.Equal(t, time.Second/30, speeds[conway.speed])
 }
+
+func TestConway_braille(t *testing.T) {
+	conway := NewConway(config.New())
+	conway.Update(tea.WindowSizeMsg{Width: 80, Height: 25})
+	assert.Equal(t, image.Pt(40, 24), conway.gameSize)
+
+	center := conway.view.Add(conway.gameSize.Div(2))
+	conway.Update(tea.KeyPressMsg{Code: 'b', Text: "b"})
+	assert.True(t, conway.braille)
+	assert.Equal(t, image.Pt(160, 96), conway.gameSize)
+	assert.Equal(t, center, conway.view.Add(conway.gameSize.Div(2)))
+
+	conway.Update(tea.KeyPressMsg{Code: 'b', Text: "b"})
+	assert.False(t, conway.braille)
+	assert.Equal(t, image.Pt(40, 24), conway.gameSize)
+}
+
+func TestConway_graphics(t *testing.T) {
+	conf := config.New()
+	conf.Graphics = graphics.Kitty.String()
+	conway := NewConway(conf)
+	assert.Equal(t, graphics.Kitty, conway.graphics)
+	conway.Update(tea.WindowSizeMsg{Width: 80, Height: 25})
+
+	_, cmd := conway.Update(commands.Conway)
+	assert.True(t, conway.visible)
+	assert.NotNil(t, cmd)
+	assert.NotContains(t, conway.View().Content, "█")
+
+	conway.Update(uv.CellSizeEvent{Width: 8, Height: 16})
+	assert.Equal(t, image.Pt(8, 16), conway.cellSize)
+
+	_, cmd = conway.Update(commands.Menu)
+	assert.False(t, conway.visible)
+	assert.NotNil(t, cmd)
+}
+
+func TestConway_zoom(t *testing.T) {
+	conway := NewConway(config.New())
+	conway.Update(commands.Conway)
+	conway.Update(tea.WindowSizeMsg{Width: 80, Height: 25})
+	conway.view = image.Point{}
+
+	conway.Update(tea.KeyPressMsg{Code: '+', Text: "+"})
+	assert.Equal(t, 2, conway.scale)
+	assert.Equal(t, image.Pt(20, 12), conway.gameSize)
+
+	conway.view = image.Point{}
+	conway.mode = ModePlace
+	conway.Update(tea.MouseClickMsg{X: 13, Y: 5, Button: tea.MouseLeft})
+	assert.True(t, conway.Pattern.Tree.Get(image.Pt(3, 2)))
+
+	for range maxScale {
+		conway.Update(tea.KeyPressMsg{Code: '+', Text: "+"})
+	}
+	assert.Equal(t, maxScale, conway.scale)
+
+	for range maxScale {
+		conway.Update(tea.KeyPressMsg{Code: '-', Text: "-"})
+	}
+	assert.Equal(t, 1, conway.scale)
+	assert.Equal(t, uint8(1), conway.level)
+}
+
+func TestConway_followOffset(t *testing.T) {
+	conway := NewConway(config.New())
+	conway.Update(commands.Conway)
+	conway.Update(tea.WindowSizeMsg{Width: 80, Height: 25})
+	conway.view = image.Point{}
+
+	conway.Pattern.Tree.SetOffset(quadtree.BigPt(5, -3))
+	conway.Update(frameMsg{})
+	assert.Equal(t, image.Pt(-5, 3), conway.view)
+
+	conway.Pattern.Tree.SetOffset(quadtree.BigPoint{X: new(big.Int).Lsh(big.NewInt(1), 100)})
+	conway.Update(frameMsg{})
+	assert.Equal(t, image.Pt(-20, -12), conway.view)
+}
+
+func TestConway_stepMenu(t *testing.T) {
+	conway := NewConway(config.New())
+	conway.Update(commands.Conway)
+	conway.Update(tea.WindowSizeMsg{Width: 80, Height: 25})
+
+	conway.Update(tea.KeyPressMsg{Code: 'j', Text: "j"})
+	require.NotNil(t, conway.stepping)
+
+	_, cmd := conway.Update(tea.KeyPressMsg{Code: tea.KeyEscape})
+	require.NotNil(t, cmd)
+	assert.Equal(t, commands.Menu, cmd())
+
+	conway.Update(commands.Menu)
+	assert.Nil(t, conway.stepping)
+}
+
+func TestConway_apgcode(t *testing.T) {
+	conway := NewConway(config.New())
+	p, err := pattern.UnmarshalAPGCode("xs4_33")
+	require.NoError(t, err)
+	conway.Pattern = p
+
+	assert.Equal(t, "xs4_33", conway.apgcode())
+
+	conway.Play()
+	t.Cleanup(conway.Pause)
+	assert.Equal(t, "(running)", conway.apgcode())
+
+	conway.Pause()
+	assert.Equal(t, "xs4_33", conway.apgcode())
+}
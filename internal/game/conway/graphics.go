@@ -0,0 +1,125 @@
+package conway
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+	"gabe565.com/cli-of-life/internal/graphics"
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// defaultCellSize is used to size Sixel images until the terminal reports its
+// cell size in pixels.
+//
+//nolint:gochecknoglobals
+var defaultCellSize = image.Pt(10, 20)
+
+// initGraphics resolves the configured graphics protocol. When set to auto,
+// the environment is checked first, then the terminal is queried for Sixel
+// support.
+func (c *Conway) initGraphics() {
+	c.cellSize = defaultCellSize
+	if c.config.ASCII {
+		return
+	}
+	if c.config.Graphics == graphics.Auto {
+		c.graphics = graphics.DetectEnv(os.Getenv)
+		c.detectGraphics = c.graphics == graphics.None
+		return
+	}
+	c.graphics, _ = graphics.Parse(c.config.Graphics)
+}
+
+// GraphicsQueries returns the terminal queries needed to finish setting up
+// graphics output.
+func (c *Conway) GraphicsQueries() tea.Cmd {
+	var cmds []tea.Cmd
+	if c.detectGraphics {
+		cmds = append(cmds, tea.Raw(ansi.RequestPrimaryDeviceAttributes))
+	}
+	if c.detectGraphics || c.graphics == graphics.Sixel {
+		cmds = append(cmds, tea.Raw(ansi.WindowOp(ansi.RequestCellSizeWinOp)))
+	}
+	return tea.Batch(cmds...)
+}
+
+func (c *Conway) updateGraphics(msg tea.Msg) {
+	switch msg := msg.(type) {
+	case uv.PrimaryDeviceAttributesEvent:
+		if c.detectGraphics {
+			c.detectGraphics = false
+			if graphics.HasSixel(msg) {
+				c.graphics = graphics.Sixel
+			}
+		}
+	case uv.CellSizeEvent:
+		if msg.Width > 0 && msg.Height > 0 {
+			c.cellSize = image.Pt(msg.Width, msg.Height)
+		}
+	}
+}
+
+func (c *Conway) graphicsEnabled() bool {
+	return c.graphics != graphics.None && c.visible && !c.debug
+}
+
+// blankView fills the game area with spaces so that text does not cover the image.
+func (c *Conway) blankView() {
+	line := strings.Repeat(" ", c.viewSize.Width) + "\n"
+	c.viewBuf.WriteString(strings.Repeat(line, max(c.viewSize.Height-1, 0)))
+}
+
+// drawGraphics renders the viewport as an image. Images can not be part of a
+// view, so they are written directly to the terminal over the blank game area.
+func (c *Conway) drawGraphics() tea.Cmd {
+	if !c.graphicsEnabled() || c.Pattern == nil || c.gameSize.X == 0 || c.gameSize.Y == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	defer func() {
+		c.metrics.RecordRender(time.Since(start))
+	}()
+
+	cols, rows := c.viewSize.Width, c.viewSize.Height-1
+	img := c.Pattern.Tree.RenderImage(image.Rectangle{Min: c.view, Max: c.view.Add(c.gameSize)}, c.level)
+	if c.graphics == graphics.Sixel {
+		img = graphics.Scale(img, image.Pt(cols*c.cellSize.X, rows*c.cellSize.Y))
+		// Sixel pixels stay on screen until overwritten, so empty cells are
+		// painted with the background to erase the previous frame.
+		img.Palette = slices.Clone(img.Palette)
+		img.Palette[0] = color.Black
+		if !c.dark {
+			img.Palette[0] = color.White
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := graphics.Encode(&buf, c.graphics, img, cols, rows); err != nil {
+		c.err = err
+		return nil
+	}
+	return tea.Raw(buf.String())
+}
+
+// clearGraphics removes the image when the game is hidden.
+func (c *Conway) clearGraphics() tea.Cmd {
+	switch c.graphics {
+	case graphics.Kitty:
+		var buf bytes.Buffer
+		if err := graphics.Clear(&buf, c.graphics); err != nil {
+			return nil
+		}
+		return tea.Raw(buf.String())
+	case graphics.Sixel:
+		return tea.ClearScreen
+	}
+	return nil
+}
@@ -42,6 +42,14 @@ func newKeymap() keymap {
 			key.WithKeys("+", "-"),
 			key.WithHelp("+/-", "zoom"),
 		),
+		braille: key.NewBinding(
+			key.WithKeys("b"),
+			key.WithHelp("b", "braille"),
+		),
+		grid: key.NewBinding(
+			key.WithKeys("g"),
+			key.WithHelp("g", "grid"),
+		),
 		speedUp: key.NewBinding(
 			key.WithKeys(">", "."),
 		),
@@ -56,11 +64,16 @@ func newKeymap() keymap {
 			key.WithKeys("t"),
 			key.WithHelp("t", "tick"),
 		),
+		jump: key.NewBinding(
+			key.WithKeys("j"),
+			key.WithHelp("j", "jump"),
+		),
 		menu: key.NewBinding(
 			key.WithKeys("esc"),
 			key.WithHelp("esc", "menu"),
 		),
 		reset: key.NewBinding(key.WithKeys("r")),
+		save:  key.NewBinding(key.WithKeys("ctrl+s")),
 		quit: key.NewBinding(
 			key.WithKeys("q", "ctrl+c"),
 			key.WithHelp("q", "quit"),
@@ -82,13 +95,17 @@ type keymap struct {
 	zoomIn    key.Binding
 	zoomOut   key.Binding
 	zoom      key.Binding
+	braille   key.Binding
+	grid      key.Binding
 	speedUp   key.Binding
 	speedDown key.Binding
 	speed     key.Binding
 	move      key.Binding
 	tick      key.Binding
+	jump      key.Binding
 	menu      key.Binding
 	reset     key.Binding
+	save      key.Binding
 	quit      key.Binding
 	debug     key.Binding
 }
@@ -99,8 +116,11 @@ func (k keymap) ShortHelp() []key.Binding {
 		k.mode,
 		k.move,
 		k.zoom,
+		k.braille,
+		k.grid,
 		k.speed,
 		k.tick,
+		k.jump,
 		k.menu,
 		k.quit,
 	}
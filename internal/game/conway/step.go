@@ -0,0 +1,88 @@
+package conway
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	tea "charm.land/bubbletea/v2"
+)
+
+const (
+	// jumpSize is the number of generations advanced by the jump key.
+	jumpSize = 1000
+	// progressDelay is how long a step must run before progress is shown.
+	progressDelay = 250 * time.Millisecond
+)
+
+// stepState tracks a step running in the background.
+type stepState struct {
+	cancel context.CancelFunc
+	start  time.Time
+	jump   bool
+	done   atomic.Uint64
+	total  atomic.Uint64
+}
+
+func (s *stepState) progress(done, total uint64) {
+	s.done.Store(done)
+	s.total.Store(total)
+}
+
+func (s *stepState) percent() float64 {
+	total := s.total.Load()
+	if total == 0 {
+		return 0
+	}
+	return float64(s.done.Load()) / float64(total)
+}
+
+func (s *stepState) visible() bool {
+	return s.jump || time.Since(s.start) > progressDelay
+}
+
+// startStep advances the pattern in a background command so that slow steps
+// don't block input handling.
+func (c *Conway) startStep(steps uint64, jump bool) tea.Cmd {
+	ctx, cancel := context.WithCancel(context.Background())
+	state := &stepState{
+		cancel: cancel,
+		start:  time.Now(),
+		jump:   jump,
+	}
+	state.total.Store(steps)
+	c.stepping = state
+
+	p, rec := c.Pattern, c.metrics
+	cmds := []tea.Cmd{
+		stepCmd(ctx, state, func(ctx context.Context) error {
+			defer cancel()
+			return stepMeasured(ctx, p, steps, rec, state.progress)
+		}),
+	}
+	if !c.spinning {
+		c.spinning = true
+		cmds = append(cmds, c.spinner.Tick)
+	}
+	return tea.Batch(cmds...)
+}
+
+func (c *Conway) cancelStep() {
+	if c.stepping != nil {
+		c.stepping.cancel()
+		c.stepping = nil
+	}
+}
+
+func (c *Conway) stepView() string {
+	s := c.stepping
+	label := "Stepping"
+	if s.jump {
+		label = "Jumping"
+	}
+	label += " " + strconv.FormatUint(s.done.Load(), 10) + "/" + strconv.FormatUint(s.total.Load(), 10) + " "
+	hint := " " + c.keymap.menu.Help().Key + " abort"
+	c.progress.SetWidth(c.viewSize.Width - len(label) - len(hint) - 2)
+	return c.spinner.View() + " " + label + c.progress.ViewAs(s.percent()) + hint
+}
@@ -0,0 +1,8 @@
+package conway
+
+import "charm.land/lipgloss/v2"
+
+//nolint:gochecknoglobals
+var errorStyle = lipgloss.NewStyle().
+	Foreground(lipgloss.Color("204")).
+	Bold(true)
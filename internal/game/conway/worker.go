@@ -0,0 +1,106 @@
+package conway
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"gabe565.com/cli-of-life/internal/metrics"
+	"gabe565.com/cli-of-life/internal/pattern"
+	"gabe565.com/cli-of-life/internal/quadtree"
+)
+
+// maxBacklog is how far the worker may fall behind the target speed before
+// the missed generations are dropped.
+const maxBacklog = time.Second
+
+// worker runs the simulation in its own goroutine. The renderer reads the
+// snapshots that stepping publishes, so a slow generation never blocks input.
+type worker struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    atomic.Pointer[error]
+}
+
+func startWorker(p *pattern.Pattern, interval time.Duration, rec *metrics.Recorder) *worker {
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &worker{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	go w.run(ctx, p, interval, rec)
+	return w
+}
+
+func (w *worker) run(ctx context.Context, p *pattern.Pattern, interval time.Duration, rec *metrics.Recorder) {
+	defer close(w.done)
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	start := time.Now()
+	var done uint64
+	for {
+		elapsed := time.Since(start)
+		if backlog := elapsed - time.Duration(done)*interval; backlog > maxBacklog {
+			start = start.Add(backlog - interval)
+			elapsed = time.Since(start)
+		}
+
+		if due := uint64(elapsed/interval) - done; due != 0 {
+			err := stepMeasured(ctx, p, due, rec, func(uint64, uint64) {
+				done++
+			})
+			if errors.Is(err, quadtree.ErrReset) {
+				// Keep playing from the reset state.
+				continue
+			}
+			if err != nil {
+				if ctx.Err() == nil {
+					w.err.Store(&err)
+				}
+				return
+			}
+			continue
+		}
+
+		timer.Reset(time.Until(start.Add(time.Duration(done+1) * interval)))
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// Stop cancels the worker and waits for the current generation to finish.
+func (w *worker) Stop() {
+	w.cancel()
+	<-w.done
+}
+
+// Err returns the error that stopped the worker, if any.
+func (w *worker) Err() error {
+	if err := w.err.Load(); err != nil {
+		return *err
+	}
+	return nil
+}
+
+// stepMeasured steps the pattern, recording its timing and allocations.
+func stepMeasured(
+	ctx context.Context, p *pattern.Pattern, steps uint64, rec *metrics.Recorder, progress quadtree.ProgressFunc,
+) error {
+	allocs := metrics.HeapAllocs()
+	start := time.Now()
+	var done uint64
+	err := p.Step(ctx, steps, func(d, total uint64) {
+		done = d
+		if progress != nil {
+			progress(d, total)
+		}
+	})
+	rec.RecordStep(done, time.Since(start), metrics.HeapAllocs()-allocs)
+	return err
+}
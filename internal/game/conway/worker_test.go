@@ -0,0 +1,26 @@
+package conway
+
+import (
+	"image"
+	"testing"
+	"time"
+
+	"gabe565.com/cli-of-life/internal/metrics"
+	"gabe565.com/cli-of-life/internal/pattern"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorker(t *testing.T) {
+	p := pattern.Default()
+	require.NoError(t, p.Tree.Set(image.Pt(0, 0), 1))
+
+	rec := metrics.New()
+	w := startWorker(p, time.Millisecond, rec)
+	time.Sleep(50 * time.Millisecond)
+	w.Stop()
+
+	require.NoError(t, w.Err())
+	assert.Positive(t, p.Tree.Stats().Generation.Int64())
+	assert.Positive(t, rec.Stats().GenerationsPerSecond)
+}
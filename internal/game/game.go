@@ -6,6 +6,7 @@ import (
 	"gabe565.com/cli-of-life/internal/game/commands"
 	"gabe565.com/cli-of-life/internal/game/conway"
 	"gabe565.com/cli-of-life/internal/game/menu"
+	uv "github.com/charmbracelet/ultraviolet"
 )
 
 func New(conf *config.Config) tea.Model {
@@ -22,7 +23,7 @@ type Game struct {
 }
 
 func (g *Game) Init() tea.Cmd {
-	return tea.Batch(g.active.Init(), tea.RequestBackgroundColor)
+	return tea.Batch(g.active.Init(), tea.RequestBackgroundColor, g.conway.GraphicsQueries())
 }
 
 func (g *Game) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -32,6 +33,8 @@ func (g *Game) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			g.menu.Update(msg)
 			g.conway.Update(msg)
 		}
+	case uv.PrimaryDeviceAttributesEvent, uv.CellSizeEvent:
+		g.conway.Update(msg)
 	case tea.BackgroundColorMsg:
 		dark := msg.IsDark()
 		g.menu.SetDark(dark)
@@ -44,7 +47,7 @@ func (g *Game) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg {
 		case commands.Conway:
 			g.active = g.conway
-		case commands.Menu:
+		case commands.Menu, commands.Save:
 			g.active = g.menu
 		}
 		if _, cmd := g.active.Update(msg); cmd != nil {
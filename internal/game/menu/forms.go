@@ -3,17 +3,24 @@ package menu
 import (
 	"errors"
 	"io/fs"
+	"log/slog"
 	"net/url"
 	"os"
 	"path"
+	"path/filepath"
+	"slices"
 	"strings"
+	"unicode"
 
 	tea "charm.land/bubbletea/v2"
 	"charm.land/huh/v2"
 	"charm.land/lipgloss/v2"
+	"gabe565.com/cli-of-life/internal/game/commands"
 	"gabe565.com/cli-of-life/internal/game/util"
 	"gabe565.com/cli-of-life/internal/pattern"
 	"gabe565.com/cli-of-life/internal/pattern/embedded"
+	"gabe565.com/cli-of-life/internal/rule"
+	"gabe565.com/cli-of-life/internal/theme"
 )
 
 const (
@@ -24,6 +31,9 @@ const (
 
 func (m *Menu) initForm() tea.Cmd {
 	m.form = m.form.WithWidth(lipgloss.Width(Title))
+	if m.config.ASCII {
+		m.form = m.form.WithTheme(huh.ThemeFunc(util.ASCIITheme))
+	}
 	cmds := make([]tea.Cmd, 0, 2)
 	cmds = append(cmds, m.form.Init())
 
@@ -63,7 +73,7 @@ func (m *Menu) patternFileForm() tea.Cmd {
 				CurrentDirectory(wd).
 				ShowSize(true).
 				ShowPermissions(false).
-				AllowedTypes(pattern.Extensions()).
+				AllowedTypes(slices.Concat(pattern.Extensions(), pattern.ArchiveExtensions())).
 				Height(15).
 				Value(&m.config.Pattern),
 		),
@@ -106,6 +116,128 @@ func (m *Menu) patternEmbeddedForm() tea.Cmd {
 	return m.initForm()
 }
 
+func (m *Menu) savePatternForm() tea.Cmd {
+	m.saveName = saveFileName(m.conway.Pattern.Name)
+	if m.saveFormat == "" {
+		m.saveFormat = pattern.ExtRLE
+	}
+	return m.saveForm()
+}
+
+func (m *Menu) saveForm() tea.Cmd {
+	p := m.conway.Pattern
+
+	// Plaintext has no rule field, so it is always read back as B3/S23.
+	var noRule string
+	if p.Rule.String() != rule.GameOfLife().String() {
+		noRule = " (drops rule " + p.Rule.String() + ")"
+	}
+
+	m.form = util.NewForm(
+		huh.NewGroup(
+			huh.NewInput().
+				Title("File Name").
+				Validate(huh.ValidateNotEmpty()).
+				Value(&m.saveName),
+			huh.NewSelect[string]().
+				Title("Format").
+				Options(
+					huh.NewOption("RLE", pattern.ExtRLE),
+					huh.NewOption("Plaintext"+noRule, pattern.ExtPlaintext),
+					huh.NewOption("Macrocell", pattern.ExtMacrocell),
+					huh.NewOption("Macrocell (gzip)", pattern.ExtMacrocellGzip),
+					huh.NewOption("Life 1.05 (.life)", pattern.ExtLifeAlt),
+					huh.NewOption("Life (.lif)", pattern.ExtLife),
+				).
+				Value(&m.saveFormat),
+		),
+	)
+	m.submit = func() tea.Cmd {
+		path := strings.TrimSpace(m.saveName)
+		if pattern.FileExt(path) != m.saveFormat {
+			path += m.saveFormat
+		}
+		if _, err := os.Stat(path); err == nil {
+			return m.overwriteForm(path)
+		}
+		return m.savePattern(path)
+	}
+	return m.initForm()
+}
+
+func (m *Menu) overwriteForm(path string) tea.Cmd {
+	var overwrite bool
+	m.form = util.NewForm(
+		huh.NewGroup(
+			huh.NewConfirm().
+				Title("Overwrite " + path + "?").
+				Affirmative("Overwrite").
+				Negative("Cancel").
+				Value(&overwrite),
+		),
+	)
+	m.submit = func() tea.Cmd {
+		if !overwrite {
+			return m.saveForm()
+		}
+		return m.savePattern(path)
+	}
+	return m.initForm()
+}
+
+func (m *Menu) savePattern(path string) tea.Cmd {
+	if err := pattern.MarshalFile(path, m.conway.Pattern); err != nil {
+		m.error = err
+		return nil
+	}
+	slog.Info("Saved pattern", "path", path)
+	return commands.ChangeView(commands.Conway)
+}
+
+// saveFileName suggests a file name based on the pattern name.
+func saveFileName(name string) string {
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r), r == '-', r == '_':
+			return unicode.ToLower(r)
+		case unicode.IsSpace(r):
+			return '_'
+		default:
+			return -1
+		}
+	}, name)
+	if name == "" {
+		return "pattern"
+	}
+	return name
+}
+
+func (m *Menu) themeForm() tea.Cmd {
+	names := theme.Names()
+	if !slices.Contains(names, m.config.Theme) {
+		names = append(names, m.config.Theme)
+	}
+	opts := make([]huh.Option[string], 0, len(names))
+	for _, name := range names {
+		opts = append(opts, huh.NewOption(name, name))
+	}
+
+	m.form = util.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Theme").
+				Options(opts...).
+				Value(&m.config.Theme),
+		),
+	)
+	m.submit = func() tea.Cmd {
+		m.applyTheme()
+		return nil
+	}
+	return m.initForm()
+}
+
 var ErrLineBreak = errors.New("line breaks are not allowed")
 
 type trimSpaceAccessor struct {
@@ -126,7 +258,7 @@ func (m *Menu) patternURLForm() tea.Cmd {
 		huh.NewGroup(
 			huh.NewText().
 				Title("Pattern Path/URL").
-				Description("  Supports direct rle/cells URLs\n  or web pages with rle/cells links.\n").
+				Description("  Supports direct rle/cells URLs, zip archives,\n  or web pages with rle/cells links.\n").
 				Validate(func(s string) error {
 					if err := ne(s); err != nil {
 						return err
@@ -153,7 +285,11 @@ func (m *Menu) patternURLForm() tea.Cmd {
 func (m *Menu) choosePatternForm(urls []string) tea.Cmd {
 	opts := make([]huh.Option[string], 0, len(urls))
 	for _, u := range urls {
-		opts = append(opts, huh.NewOption(path.Base(u), u))
+		name := path.Base(u)
+		if _, entry, found := strings.Cut(u, "!/"); found {
+			name = entry
+		}
+		opts = append(opts, huh.NewOption(name, u))
 	}
 
 	m.form = util.NewForm(
@@ -161,6 +297,7 @@ func (m *Menu) choosePatternForm(urls []string) tea.Cmd {
 			huh.NewSelect[string]().
 				Title("Choose Pattern").
 				Options(opts...).
+				Height(15).
 				Value(&m.config.Pattern),
 		),
 	)
@@ -22,6 +22,7 @@ func newKeymap() keymap {
 			key.WithKeys("esc"),
 			key.WithHelp("esc", "resume"),
 		),
+		save: key.NewBinding(key.WithKeys("ctrl+s")),
 		quit: key.NewBinding(
 			key.WithKeys("q", "ctrl+c"),
 			key.WithHelp("q", "quit"),
@@ -34,6 +35,7 @@ type keymap struct {
 	down   key.Binding
 	choose key.Binding
 	resume key.Binding
+	save   key.Binding
 	quit   key.Binding
 }
 
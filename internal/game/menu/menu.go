@@ -2,6 +2,7 @@ package menu
 
 import (
 	"errors"
+	"strings"
 
 	"charm.land/bubbles/v2/help"
 	"charm.land/bubbles/v2/key"
@@ -12,8 +13,10 @@ import (
 	"gabe565.com/cli-of-life/internal/game/commands"
 	"gabe565.com/cli-of-life/internal/game/components/buttons"
 	"gabe565.com/cli-of-life/internal/game/conway"
+	"gabe565.com/cli-of-life/internal/game/util"
 	"gabe565.com/cli-of-life/internal/pattern"
 	"gabe565.com/cli-of-life/internal/quadtree"
+	"gabe565.com/cli-of-life/internal/theme"
 	zone "github.com/lrstanley/bubblezone/v2"
 )
 
@@ -32,6 +35,8 @@ const (
 	BtnReset  = "Reset Game"
 	BtnNew    = "New Game"
 	BtnLoad   = "Load Pattern"
+	BtnSave   = "Save Pattern"
+	BtnTheme  = "Change Theme"
 	BtnQuit   = "Quit"
 )
 
@@ -39,20 +44,29 @@ func NewMenu(conf *config.Config, conway *conway.Conway) *Menu {
 	zone.NewGlobal()
 	m := &Menu{
 		config: conf,
+		title:  Title,
 		keymap: newKeymap(),
 		help:   help.New(),
 		styles: newStyles(),
 
 		conway:  conway,
-		buttons: buttons.New(BtnResume, BtnReset, BtnNew, BtnLoad, BtnQuit),
+		buttons: buttons.New(BtnResume, BtnReset, BtnNew, BtnLoad, BtnSave, BtnTheme, BtnQuit),
+	}
+	m.setGameButtonsHidden(true)
+	m.applyTheme()
+	if conf.ASCII {
+		m.title = strings.ReplaceAll(Title, "█", "#")
+		m.buttons.SetASCII(true)
+		util.ASCIIHelp(&m.help)
+		m.keymap.up.SetHelp("w", "up")
+		m.keymap.down.SetHelp("s", "down")
 	}
-	m.buttons.List[0].Hidden = true
-	m.buttons.List[1].Hidden = true
 	return m
 }
 
 type Menu struct {
 	config *config.Config
+	title  string
 	size   tea.WindowSizeMsg
 	keymap keymap
 	help   help.Model
@@ -63,6 +77,9 @@ type Menu struct {
 	buttons    *buttons.Buttons
 	form       *huh.Form
 	patternSrc string
+	submit     func() tea.Cmd
+	saveName   string
+	saveFormat string
 
 	error error
 }
@@ -94,6 +111,10 @@ func (m *Menu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch m.form.State {
 		case huh.StateCompleted:
 			m.form = nil
+			if submit := m.submit; submit != nil {
+				m.submit = nil
+				return m, submit()
+			}
 			defer func() {
 				m.patternSrc = ""
 			}()
@@ -110,6 +131,7 @@ func (m *Menu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		case huh.StateAborted:
 			m.form = nil
+			m.submit = nil
 			return m, nil
 		default:
 			return m, cmd
@@ -118,11 +140,14 @@ func (m *Menu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case commands.ViewMsg:
-		if msg == commands.Menu {
-			empty := m.conway.Pattern.Tree.IsEmpty()
-			m.buttons.List[0].Hidden = empty
-			m.buttons.List[1].Hidden = empty
+		switch msg {
+		case commands.Menu:
+			m.setGameButtonsHidden(m.conway.Pattern.Tree.IsEmpty())
 			m.buttons.Active = 0
+		case commands.Save:
+			m.setGameButtonsHidden(m.conway.Pattern.Tree.IsEmpty())
+			m.buttons.Active = 0
+			return m, m.savePatternForm()
 		}
 	case tea.KeyPressMsg:
 		switch {
@@ -133,6 +158,11 @@ func (m *Menu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case key.Matches(msg, m.keymap.choose):
 			m.error = nil
 			return m, m.handleButtonPress(m.buttons.Current())
+		case key.Matches(msg, m.keymap.save):
+			if m.conway.Pattern != nil {
+				m.error = nil
+				return m, m.savePatternForm()
+			}
 		case key.Matches(msg, m.keymap.resume):
 			m.error = nil
 			return m, commands.ChangeView(commands.Conway)
@@ -160,6 +190,16 @@ func (m *Menu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
+// setGameButtonsHidden hides the buttons which act on the current game.
+func (m *Menu) setGameButtonsHidden(hidden bool) {
+	for _, btn := range m.buttons.List {
+		switch btn.Name {
+		case BtnResume, BtnReset, BtnSave:
+			btn.Hidden = hidden
+		}
+	}
+}
+
 func (m *Menu) LoadPattern() tea.Cmd {
 	quadtree.ResetCache()
 	p, err := pattern.New(m.config)
@@ -176,13 +216,24 @@ func (m *Menu) LoadPattern() tea.Cmd {
 	return commands.ChangeView(commands.Conway)
 }
 
+// applyTheme updates the menu and game colors from the configured theme.
+func (m *Menu) applyTheme() {
+	t, err := theme.Parse(m.config.Theme)
+	if err != nil {
+		m.error = err
+		return
+	}
+	m.buttons.SetTheme(t)
+	m.conway.SetTheme(t)
+}
+
 func (m *Menu) SetDark(dark bool) {
 	m.help.Styles = help.DefaultStyles(dark)
 	m.buttons.SetDark(dark)
 }
 
 func (m *Menu) View() tea.View {
-	views := []string{Title}
+	views := []string{m.title}
 
 	if m.form == nil {
 		if m.conway.Pattern != nil {
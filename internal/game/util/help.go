@@ -0,0 +1,10 @@
+package util
+
+import "charm.land/bubbles/v2/help"
+
+// ASCIIHelp replaces the non-ASCII separators used by the help view.
+func ASCIIHelp(h *help.Model) {
+	h.ShortSeparator = " - "
+	h.FullSeparator = "   "
+	h.Ellipsis = "..."
+}
@@ -1,8 +1,12 @@
 package util
 
 import (
+	"strings"
+
+	"charm.land/bubbles/v2/help"
 	"charm.land/bubbles/v2/key"
 	"charm.land/huh/v2"
+	"charm.land/lipgloss/v2"
 )
 
 func NewForm(groups ...*huh.Group) *huh.Form {
@@ -29,3 +33,39 @@ func addKeys(b key.Binding, keys ...string) key.Binding {
 	b.SetKeys(append(b.Keys(), keys...)...)
 	return b
 }
+
+// ASCIITheme is a form theme which only uses ASCII characters and no colors.
+func ASCIITheme(isDark bool) *huh.Styles {
+	t := huh.ThemeBase(isDark)
+
+	t.Focused.Base = t.Focused.Base.BorderStyle(lipgloss.Border{Left: "|"})
+	t.Focused.Card = t.Focused.Base
+	t.Focused.NextIndicator = t.Focused.NextIndicator.SetString(">")
+	t.Focused.PrevIndicator = t.Focused.PrevIndicator.SetString("<")
+	t.Focused.SelectedPrefix = lipgloss.NewStyle().SetString("[x] ")
+	t.Focused.TextInput.Placeholder = lipgloss.NewStyle()
+
+	// Buttons are usually told apart by color, so mark the focused one with brackets.
+	button := lipgloss.NewStyle().Padding(0, 1).MarginRight(1).BorderLeft(true).BorderRight(true)
+	t.Focused.FocusedButton = button.BorderStyle(lipgloss.Border{Left: "[", Right: "]"})
+	t.Focused.BlurredButton = button.BorderStyle(lipgloss.Border{Left: " ", Right: " "})
+
+	// The help separators can't be set through the form, so replace them as they render.
+	replace := func(oldnew ...string) lipgloss.Style {
+		r := strings.NewReplacer(oldnew...)
+		return lipgloss.NewStyle().Transform(r.Replace)
+	}
+	t.Help = help.Styles{
+		Ellipsis:       replace("…", "..."),
+		ShortSeparator: replace("•", "-"),
+		FullSeparator:  replace("•", "-"),
+	}
+
+	t.Blurred = t.Focused
+	t.Blurred.Base = t.Blurred.Base.BorderStyle(lipgloss.HiddenBorder())
+	t.Blurred.Card = t.Blurred.Base
+	t.Blurred.MultiSelectSelector = lipgloss.NewStyle().SetString("  ")
+	t.Blurred.NextIndicator = lipgloss.NewStyle()
+	t.Blurred.PrevIndicator = lipgloss.NewStyle()
+	return t
+}
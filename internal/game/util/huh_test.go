@@ -0,0 +1,34 @@
+package util
+
+import (
+	"regexp"
+	"testing"
+
+	tea "charm.land/bubbletea/v2"
+	"charm.land/huh/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestASCIITheme(t *testing.T) {
+	var name string
+	var overwrite bool
+	form := NewForm(
+		huh.NewGroup(
+			huh.NewInput().Title("File Name").Placeholder("pattern").Value(&name),
+			huh.NewSelect[string]().Title("Format").Options(huh.NewOptions("RLE", "Plaintext")...),
+			huh.NewConfirm().Title("Overwrite?").Value(&overwrite),
+		),
+	).WithTheme(huh.ThemeFunc(ASCIITheme))
+	form.Init()
+	form.Update(tea.WindowSizeMsg{Width: 80, Height: 24})
+
+	view := form.View()
+	assert.Contains(t, view, "File Name")
+	// Only the input's reverse video cursor may be styled.
+	for _, sgr := range regexp.MustCompile("\x1b\\[[0-9;]*m").FindAllString(view, -1) {
+		assert.Contains(t, []string{"\x1b[7m", "\x1b[m"}, sgr)
+	}
+	for _, r := range view {
+		assert.Less(t, r, rune(0x80), "non-ASCII rune %q in %q", r, view)
+	}
+}
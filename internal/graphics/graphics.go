@@ -0,0 +1,164 @@
+package graphics
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/ansi/kitty"
+	"github.com/charmbracelet/x/ansi/sixel"
+)
+
+// Protocol is a terminal graphics protocol.
+type Protocol uint8
+
+const (
+	None Protocol = iota
+	Sixel
+	Kitty
+)
+
+const Auto = "auto"
+
+func (p Protocol) String() string {
+	switch p {
+	case Sixel:
+		return "sixel"
+	case Kitty:
+		return "kitty"
+	default:
+		return "none"
+	}
+}
+
+func Names() []string {
+	return []string{Auto, Sixel.String(), Kitty.String(), None.String()}
+}
+
+var ErrUnknownProtocol = errors.New("unknown graphics protocol")
+
+// Parse returns the protocol with the given name. Auto is not handled here
+// since it must be resolved by inspecting the terminal.
+func Parse(name string) (Protocol, error) {
+	switch strings.ToLower(name) {
+	case None.String(), "":
+		return None, nil
+	case Sixel.String():
+		return Sixel, nil
+	case Kitty.String():
+		return Kitty, nil
+	default:
+		return None, fmt.Errorf("%w: %q", ErrUnknownProtocol, name)
+	}
+}
+
+// DetectEnv guesses the protocol from environment variables set by terminals
+// known to support one. Sixel support can also be queried with
+// ansi.RequestPrimaryDeviceAttributes and checked with HasSixel.
+func DetectEnv(getenv func(string) string) Protocol {
+	switch {
+	case getenv("KITTY_WINDOW_ID") != "",
+		getenv("TERM") == "xterm-kitty",
+		getenv("TERM") == "xterm-ghostty",
+		getenv("TERM_PROGRAM") == "ghostty",
+		getenv("TERM_PROGRAM") == "WezTerm":
+		return Kitty
+	case getenv("TERM") == "foot",
+		getenv("TERM") == "mlterm",
+		strings.HasPrefix(getenv("TERM"), "yaft"),
+		getenv("TERM_PROGRAM") == "iTerm.app":
+		return Sixel
+	default:
+		return None
+	}
+}
+
+// HasSixel reports whether a primary device attributes response advertises
+// Sixel graphics.
+func HasSixel(attrs []int) bool {
+	return slices.Contains(attrs, 4)
+}
+
+// imageID identifies the image in the Kitty protocol so that each frame
+// replaces the previous one instead of stacking placements.
+const imageID = 565
+
+// Encode writes img using the protocol. The image is drawn from the top-left
+// corner of the screen, and the cursor is restored afterward. Kitty scales the
+// image to fill the given number of columns and rows, while Sixel images are
+// drawn at their native size.
+func Encode(w io.Writer, p Protocol, img image.Image, cols, rows int) error {
+	var buf bytes.Buffer
+	buf.WriteString(ansi.SaveCursor)
+	buf.WriteString(ansi.CursorHomePosition)
+	switch p {
+	case Sixel:
+		var payload bytes.Buffer
+		if err := (&sixel.Encoder{}).Encode(&payload, img); err != nil {
+			return fmt.Errorf("sixel: %w", err)
+		}
+		buf.WriteString(ansi.SixelGraphics(0, 1, 0, payload.Bytes()))
+	case Kitty:
+		size := img.Bounds().Size()
+		if err := kitty.EncodeGraphics(&buf, img, &kitty.Options{
+			Action:          kitty.TransmitAndPut,
+			Transmission:    kitty.Direct,
+			Quite:           2,
+			ID:              imageID,
+			Format:          kitty.RGBA,
+			ImageWidth:      size.X,
+			ImageHeight:     size.Y,
+			Compression:     kitty.Zlib,
+			Chunk:           true,
+			Columns:         cols,
+			Rows:            rows,
+			DoNotMoveCursor: true,
+		}); err != nil {
+			return fmt.Errorf("kitty: %w", err)
+		}
+	default:
+		return nil
+	}
+	buf.WriteString(ansi.RestoreCursor)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// Clear removes any image drawn with the protocol. Sixel images are part of the
+// screen contents, so the screen must be repainted instead.
+func Clear(w io.Writer, p Protocol) error {
+	if p != Kitty {
+		return nil
+	}
+	opts := kitty.Options{
+		Action:          kitty.Delete,
+		Delete:          kitty.DeleteID,
+		DeleteResources: true,
+		ID:              imageID,
+		Quite:           2,
+	}
+	_, err := io.WriteString(w, ansi.KittyGraphics(nil, opts.Options()...))
+	return err
+}
+
+// Scale resizes img to the given size using nearest-neighbor sampling so that
+// cell edges stay sharp.
+func Scale(img *image.Paletted, size image.Point) *image.Paletted {
+	src := img.Bounds().Size()
+	dst := image.NewPaletted(image.Rectangle{Max: size}, img.Palette)
+	if src.X == 0 || src.Y == 0 {
+		return dst
+	}
+	for y := range size.Y {
+		sy := y * src.Y / size.Y
+		for x := range size.X {
+			dst.Pix[y*dst.Stride+x] = img.Pix[sy*img.Stride+x*src.X/size.X]
+		}
+	}
+	return dst
+}
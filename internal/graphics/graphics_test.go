@@ -0,0 +1,177 @@
+package graphics
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/ansi/kitty"
+	"github.com/charmbracelet/x/ansi/sixel"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testImage() *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, 5, 6), color.Palette{color.Black, color.White})
+	for _, p := range []image.Point{{1, 0}, {2, 1}, {0, 2}, {1, 2}, {2, 2}, {4, 5}} {
+		img.SetColorIndex(p.X, p.Y, 1)
+	}
+	return img
+}
+
+func assertSameImage(t *testing.T, want, got image.Image) {
+	t.Helper()
+	require.Equal(t, want.Bounds().Size(), got.Bounds().Size())
+	for y := range want.Bounds().Dy() {
+		for x := range want.Bounds().Dx() {
+			wr, wg, wb, _ := want.At(x, y).RGBA()
+			gr, gg, gb, _ := got.At(got.Bounds().Min.X+x, got.Bounds().Min.Y+y).RGBA()
+			assert.Equal(t, [3]uint32{wr, wg, wb}, [3]uint32{gr, gg, gb}, "(%d, %d)", x, y)
+		}
+	}
+}
+
+// unwrap checks that s restores the cursor position and returns the sequence between.
+func unwrap(t *testing.T, s string) string {
+	t.Helper()
+	s, ok := strings.CutPrefix(s, ansi.SaveCursor+ansi.CursorHomePosition)
+	require.True(t, ok)
+	s, ok = strings.CutSuffix(s, ansi.RestoreCursor)
+	require.True(t, ok)
+	return s
+}
+
+func TestEncode(t *testing.T) {
+	t.Run("sixel", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, Encode(&buf, Sixel, testImage(), 4, 2))
+
+		s := unwrap(t, buf.String())
+		_, s, ok := strings.Cut(s, "q")
+		require.True(t, ok)
+		s, ok = strings.CutSuffix(s, "\x1b\\")
+		require.True(t, ok)
+
+		got, err := (&sixel.Decoder{}).Decode(strings.NewReader(s))
+		require.NoError(t, err)
+		assertSameImage(t, testImage(), got)
+	})
+
+	t.Run("kitty", func(t *testing.T) {
+		img := image.NewPaletted(image.Rect(0, 0, 200, 100), color.Palette{color.Black, color.White})
+		for i := range img.Pix {
+			img.Pix[i] = uint8(rand.N(2)) //nolint:gosec
+		}
+		var buf bytes.Buffer
+		require.NoError(t, Encode(&buf, Kitty, img, 40, 10))
+
+		var payload []byte
+		chunks := strings.Split(unwrap(t, buf.String()), "\x1b\\")
+		require.Greater(t, len(chunks), 2, "image should be sent in chunks")
+		assert.Empty(t, chunks[len(chunks)-1])
+		for i, chunk := range chunks[:len(chunks)-1] {
+			chunk, ok := strings.CutPrefix(chunk, "\x1b_G")
+			require.True(t, ok)
+			opts, data, _ := strings.Cut(chunk, ";")
+			if i == 0 {
+				for _, want := range []string{"a=T", "i=565", "s=200", "v=100", "c=40", "r=10", "o=z", "C=1"} {
+					assert.Contains(t, strings.Split(opts, ","), want)
+				}
+			}
+			b, err := base64.StdEncoding.DecodeString(data)
+			require.NoError(t, err)
+			payload = append(payload, b...)
+		}
+
+		got, err := (&kitty.Decoder{Decompress: true, Width: 200, Height: 100}).Decode(bytes.NewReader(payload))
+		require.NoError(t, err)
+		assertSameImage(t, img, got)
+	})
+
+	t.Run("none", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, Encode(&buf, None, testImage(), 4, 2))
+		assert.Empty(t, buf.String())
+	})
+}
+
+func TestClear(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, Clear(&buf, Kitty))
+	assert.Equal(t, "\x1b_Gq=2,i=565,d=I,a=d\x1b\\", buf.String())
+
+	buf.Reset()
+	require.NoError(t, Clear(&buf, Sixel))
+	assert.Empty(t, buf.String())
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Protocol
+		wantErr require.ErrorAssertionFunc
+	}{
+		{"", None, require.NoError},
+		{"none", None, require.NoError},
+		{"sixel", Sixel, require.NoError},
+		{"Kitty", Kitty, require.NoError},
+		{"auto", None, require.Error},
+		{"iterm", None, require.Error},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.name)
+			tt.wantErr(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestDetectEnv(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want Protocol
+	}{
+		{"empty", nil, None},
+		{"xterm", map[string]string{"TERM": "xterm-256color"}, None},
+		{"kitty", map[string]string{"TERM": "xterm-kitty"}, Kitty},
+		{"kitty window", map[string]string{"TERM": "screen", "KITTY_WINDOW_ID": "1"}, Kitty},
+		{"ghostty", map[string]string{"TERM_PROGRAM": "ghostty"}, Kitty},
+		{"wezterm", map[string]string{"TERM_PROGRAM": "WezTerm"}, Kitty},
+		{"foot", map[string]string{"TERM": "foot"}, Sixel},
+		{"yaft", map[string]string{"TERM": "yaft-256color"}, Sixel},
+		{"iterm", map[string]string{"TERM_PROGRAM": "iTerm.app"}, Sixel},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DetectEnv(func(key string) string { return tt.env[key] })
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestHasSixel(t *testing.T) {
+	assert.True(t, HasSixel([]int{62, 4, 22}))
+	assert.False(t, HasSixel([]int{62, 22}))
+	assert.False(t, HasSixel(nil))
+}
+
+func TestScale(t *testing.T) {
+	img := testImage()
+	got := Scale(img, image.Pt(10, 18))
+	assert.Equal(t, image.Pt(10, 18), got.Bounds().Size())
+	for y := range 18 {
+		for x := range 10 {
+			assert.Equal(t, img.ColorIndexAt(x/2, y/3), got.ColorIndexAt(x, y), "(%d, %d)", x, y)
+		}
+	}
+
+	empty := Scale(image.NewPaletted(image.Rectangle{}, img.Palette), image.Pt(4, 4))
+	assert.Equal(t, image.Pt(4, 4), empty.Bounds().Size())
+}
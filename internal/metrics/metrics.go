@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// DefaultWindow is the span of time that rolling measurements cover.
+const DefaultWindow = 2 * time.Second
+
+const (
+	allocsMetric   = "/gc/heap/allocs:objects"
+	heapMetric     = "/memory/classes/heap/objects:bytes"
+	gcPausesMetric = "/sched/pauses/total/gc:seconds"
+	gcCyclesMetric = "/gc/cycles/total:gc-cycles"
+)
+
+// Recorder collects rolling simulation, rendering and runtime measurements.
+type Recorder struct {
+	generations *Window
+	stepTime    *Window
+	renderTime  *Window
+	allocs      *Window
+	gcPauses    *Window
+
+	mu          sync.Mutex
+	samples     []metrics.Sample
+	pauseCounts []uint64
+	gcCycles    uint64
+}
+
+func New() *Recorder {
+	return &Recorder{
+		generations: NewWindow(DefaultWindow),
+		stepTime:    NewWindow(DefaultWindow),
+		renderTime:  NewWindow(DefaultWindow),
+		allocs:      NewWindow(DefaultWindow),
+		gcPauses:    NewWindow(DefaultWindow),
+		samples: []metrics.Sample{
+			{Name: allocsMetric},
+			{Name: heapMetric},
+			{Name: gcPausesMetric},
+			{Name: gcCyclesMetric},
+		},
+	}
+}
+
+// Stats is a point-in-time summary of a Recorder.
+type Stats struct {
+	GenerationsPerSecond float64
+	StepTime             time.Duration
+	RenderTime           time.Duration
+	// AllocsPerStep is the number of heap objects allocated per generation.
+	// The runtime only counts allocations for the whole process, so anything
+	// allocated concurrently with a step is included.
+	AllocsPerStep float64
+	HeapInUse     uint64
+	GCPauses      int
+	GCPauseTime   time.Duration
+	GCCycles      uint64
+}
+
+// HeapAllocs returns the cumulative number of heap objects allocated by the
+// process. Callers sample it before and after a step and pass the difference
+// to RecordStep.
+func HeapAllocs() uint64 {
+	var s [1]metrics.Sample
+	s[0].Name = allocsMetric
+	metrics.Read(s[:])
+	if s[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return s[0].Value.Uint64()
+}
+
+// RecordStep records that generations were computed in d, allocating allocs
+// heap objects.
+func (r *Recorder) RecordStep(generations uint64, d time.Duration, allocs uint64) {
+	if generations == 0 {
+		return
+	}
+	n := int(generations) //nolint:gosec
+	r.generations.AddN(float64(generations), n)
+	r.stepTime.AddN(float64(d), n)
+	r.allocs.AddN(float64(allocs), n)
+}
+
+// RecordRender records that a frame took d to render.
+func (r *Recorder) RecordRender(d time.Duration) {
+	r.renderTime.Add(float64(d))
+}
+
+// Stats samples the runtime and returns the current measurements.
+func (r *Recorder) Stats() Stats {
+	heap := r.sampleRuntime()
+	return Stats{
+		GenerationsPerSecond: r.generations.Rate(),
+		StepTime:             time.Duration(r.stepTime.Mean()),
+		RenderTime:           time.Duration(r.renderTime.Mean()),
+		AllocsPerStep:        r.allocs.Mean(),
+		HeapInUse:            heap,
+		GCPauses:             r.gcPauses.Count(),
+		GCPauseTime:          time.Duration(r.gcPauses.Sum() * float64(time.Second)),
+		GCCycles:             r.gcCycles,
+	}
+}
+
+// sampleRuntime reads runtime metrics, feeding new GC pauses into the rolling
+// window, and returns the bytes of heap in use.
+func (r *Recorder) sampleRuntime() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	metrics.Read(r.samples)
+	var heap uint64
+	for _, s := range r.samples {
+		switch s.Name {
+		case heapMetric:
+			if s.Value.Kind() == metrics.KindUint64 {
+				heap = s.Value.Uint64()
+			}
+		case gcCyclesMetric:
+			if s.Value.Kind() == metrics.KindUint64 {
+				r.gcCycles = s.Value.Uint64()
+			}
+		case gcPausesMetric:
+			if s.Value.Kind() == metrics.KindFloat64Histogram {
+				r.addPauses(s.Value.Float64Histogram())
+			}
+		}
+	}
+	return heap
+}
+
+// addPauses diffs the cumulative pause histogram against the previous sample.
+// Each new pause is approximated by the midpoint of its bucket.
+func (r *Recorder) addPauses(h *metrics.Float64Histogram) {
+	first := r.pauseCounts == nil
+	if len(r.pauseCounts) != len(h.Counts) {
+		r.pauseCounts = make([]uint64, len(h.Counts))
+	}
+	for i, count := range h.Counts {
+		if n := count - r.pauseCounts[i]; n != 0 && !first {
+			lo, hi := h.Buckets[i], h.Buckets[i+1]
+			if lo < 0 || hi-lo > 1 {
+				// Open-ended buckets have an infinite bound.
+				lo = max(lo, 0)
+				hi = lo
+			}
+			r.gcPauses.AddN(float64(n)*(lo+hi)/2, int(n)) //nolint:gosec
+		}
+		r.pauseCounts[i] = count
+	}
+}
@@ -0,0 +1,94 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// Window accumulates samples over a sliding time window.
+type Window struct {
+	mu      sync.Mutex
+	size    time.Duration
+	samples []sample
+	now     func() time.Time
+}
+
+type sample struct {
+	t     time.Time
+	sum   float64
+	count int
+}
+
+func NewWindow(size time.Duration) *Window {
+	return &Window{
+		size: size,
+		now:  time.Now,
+	}
+}
+
+// Add records a sample. Samples closer together than 1% of the window are
+// merged to bound memory use at high rates.
+func (w *Window) Add(v float64) {
+	w.AddN(v, 1)
+}
+
+// AddN records n samples which sum to v.
+func (w *Window) AddN(v float64, n int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := w.now()
+	if l := len(w.samples); l != 0 && now.Sub(w.samples[l-1].t) < w.size/100 {
+		w.samples[l-1].sum += v
+		w.samples[l-1].count += n
+	} else {
+		w.samples = append(w.samples, sample{t: now, sum: v, count: n})
+	}
+	w.prune(now)
+}
+
+func (w *Window) prune(now time.Time) {
+	var i int
+	for i < len(w.samples) && now.Sub(w.samples[i].t) > w.size {
+		i++
+	}
+	w.samples = w.samples[i:]
+}
+
+func (w *Window) totals() (float64, int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.prune(w.now())
+	var sum float64
+	var count int
+	for _, s := range w.samples {
+		sum += s.sum
+		count += s.count
+	}
+	return sum, count
+}
+
+// Sum returns the sum of all samples in the window.
+func (w *Window) Sum() float64 {
+	sum, _ := w.totals()
+	return sum
+}
+
+// Count returns the number of samples in the window.
+func (w *Window) Count() int {
+	_, count := w.totals()
+	return count
+}
+
+// Mean returns the average sample value in the window.
+func (w *Window) Mean() float64 {
+	sum, count := w.totals()
+	if count == 0 {
+		return 0
+	}
+	return sum / float64(count)
+}
+
+// Rate returns the sum of all samples per second.
+func (w *Window) Rate() float64 {
+	return w.Sum() / w.size.Seconds()
+}
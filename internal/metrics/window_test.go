@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWindow(t *testing.T) {
+	now := time.Unix(0, 0)
+	w := NewWindow(time.Second)
+	w.now = func() time.Time { return now }
+
+	for i := range 10 {
+		now = now.Add(50 * time.Millisecond)
+		w.Add(float64(i))
+	}
+	assert.Equal(t, 10, w.Count())
+	assert.InDelta(t, 45, w.Sum(), 0.001)
+	assert.InDelta(t, 4.5, w.Mean(), 0.001)
+	assert.InDelta(t, 45, w.Rate(), 0.001)
+
+	t.Run("old samples expire", func(t *testing.T) {
+		now = now.Add(800 * time.Millisecond)
+		assert.Equal(t, 5, w.Count())
+		assert.InDelta(t, 35, w.Sum(), 0.001)
+
+		now = now.Add(time.Second)
+		assert.Zero(t, w.Count())
+		assert.Zero(t, w.Mean())
+	})
+
+	t.Run("close samples merge", func(t *testing.T) {
+		w.AddN(4, 2)
+		w.AddN(6, 3)
+		assert.Len(t, w.samples, 1)
+		assert.Equal(t, 5, w.Count())
+		assert.InDelta(t, 2, w.Mean(), 0.001)
+	})
+}
+
+func TestHeapAllocs(t *testing.T) {
+	before := HeapAllocs()
+	// Large objects are counted as they are allocated.
+	bufs := make([][]byte, 10)
+	for i := range bufs {
+		bufs[i] = make([]byte, 1<<20)
+	}
+	runtime.KeepAlive(bufs)
+	assert.GreaterOrEqual(t, HeapAllocs()-before, uint64(len(bufs)))
+}
+
+func TestRecorder(t *testing.T) {
+	r := New()
+	r.RecordStep(4, 4*time.Millisecond, 40)
+	r.RecordRender(2 * time.Millisecond)
+	r.RecordRender(2 * time.Millisecond)
+
+	stats := r.Stats()
+	assert.Equal(t, time.Millisecond, stats.StepTime)
+	assert.Equal(t, 2*time.Millisecond, stats.RenderTime)
+	assert.InDelta(t, 10, stats.AllocsPerStep, 0.001)
+	assert.InDelta(t, 4/DefaultWindow.Seconds(), stats.GenerationsPerSecond, 0.001)
+	assert.Positive(t, stats.HeapInUse)
+}
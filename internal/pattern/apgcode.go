@@ -0,0 +1,33 @@
+package pattern
+
+import (
+	"fmt"
+
+	"gabe565.com/cli-of-life/internal/apgcode"
+)
+
+// UnmarshalAPGCode loads the object described by a Catagolue apgcode, like
+// "xq4_153" for the glider.
+func UnmarshalAPGCode(code string) (*Pattern, error) {
+	cells, err := apgcode.Decode(code)
+	if err != nil {
+		return nil, fmt.Errorf("apgcode: %w", err)
+	}
+
+	pattern := Default()
+	pattern.Name = code
+	builder := newBuilder()
+	for _, p := range cells {
+		if err := builder.Set(p); err != nil {
+			return nil, fmt.Errorf("apgcode: %w", err)
+		}
+	}
+	pattern.Tree.SetCells(builder.Node())
+	pattern.Tree.SetReset()
+	return pattern, nil
+}
+
+// APGCode returns the canonical apgcode of the pattern's current generation.
+func (p *Pattern) APGCode() (string, error) {
+	return apgcode.Encode(p.Tree.Snapshot().Root().All(), p.Rule)
+}
@@ -0,0 +1,32 @@
+package pattern
+
+import (
+	"bytes"
+	"testing"
+
+	"gabe565.com/cli-of-life/internal/apgcode"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalAPGCode(t *testing.T) {
+	got, err := UnmarshalAPGCode("xq4_153")
+	require.NoError(t, err)
+	assert.Equal(t, "xq4_153", got.Name)
+	assert.Equal(t, [][]int{{1, 1, 1}, {0, 0, 1}, {0, 1, 0}}, got.Tree.ToSlice())
+
+	_, err = UnmarshalAPGCode("xq4")
+	require.ErrorIs(t, err, apgcode.ErrInvalidCode)
+}
+
+func TestPattern_APGCode(t *testing.T) {
+	glider, err := UnmarshalRLE(bytes.NewReader(gliderRLE))
+	require.NoError(t, err)
+
+	for range 4 {
+		code, err := glider.APGCode()
+		require.NoError(t, err)
+		assert.Equal(t, "xq4_153", code)
+		require.NoError(t, glider.Step(t.Context(), 1, nil))
+	}
+}
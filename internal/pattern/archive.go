@@ -0,0 +1,138 @@
+package pattern
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+const (
+	ExtGzip  = ".gz"
+	ExtBzip2 = ".bz2"
+	ExtZip   = ".zip"
+
+	// archiveSep separates an archive from the path of an entry inside it, like
+	// "all.zip!/glider.rle".
+	archiveSep = "!/"
+)
+
+// ArchiveExtensions returns the extensions of compressed files and archives
+// which patterns can be loaded from.
+func ArchiveExtensions() []string {
+	return []string{ExtGzip, ExtBzip2, ExtZip}
+}
+
+var ErrNoArchivePatterns = errors.New("no patterns found in archive")
+
+//nolint:gochecknoglobals
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+)
+
+// cutArchivePath splits a path like "all.zip!/glider.rle" into the archive and
+// the entry. If there is no entry, found is false.
+func cutArchivePath(s string) (string, string, bool) {
+	i := strings.Index(strings.ToLower(s), ExtZip+archiveSep)
+	if i == -1 {
+		return s, "", false
+	}
+	i += len(ExtZip)
+	return s[:i], s[i+len(archiveSep):], true
+}
+
+// isPatternName reports whether a file name has a pattern extension, optionally
+// followed by a compression extension.
+func isPatternName(name string) bool {
+	name = strings.ToLower(name)
+	for _, ext := range []string{ExtGzip, ExtBzip2} {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return slices.Contains(Extensions(), FileExt(name))
+}
+
+// decompress returns a reader for the decompressed contents of a .gz or .bz2
+// file, and the name without the compression extension.
+func decompress(name string, r io.Reader) (io.Reader, string, error) {
+	ext := FileExt(name)
+	if ext == ExtMacrocellGzip {
+		ext = ExtGzip
+	}
+	name = name[:len(name)-len(ext)]
+	switch ext {
+	case ExtGzip:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, name, fmt.Errorf("gzip: %w", err)
+		}
+		return gz, name, nil
+	case ExtBzip2:
+		return bzip2.NewReader(r), name, nil
+	default:
+		return nil, name, fmt.Errorf("%w: %q", ErrUnknownFormat, ext)
+	}
+}
+
+// decompressDetected decompresses buf if it starts with gzip or bzip2 magic
+// bytes. Only a single layer is decompressed.
+func decompressDetected(buf []byte) ([]byte, error) {
+	var r io.Reader
+	switch {
+	case bytes.HasPrefix(buf, gzipMagic):
+		gz, err := gzip.NewReader(bytes.NewReader(buf))
+		if err != nil {
+			return nil, fmt.Errorf("gzip: %w", err)
+		}
+		r = gz
+	case bytes.HasPrefix(buf, bzip2Magic):
+		r = bzip2.NewReader(bytes.NewReader(buf))
+	default:
+		return buf, nil
+	}
+	buf, err := io.ReadAll(limitReader(r))
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
+
+// unmarshalZip loads an entry from a zip archive. If entry is empty, the
+// archive's only pattern is loaded, or if there are several, a
+// MultiplePatternsError lists them as "archive.zip!/entry" paths.
+func unmarshalZip(name string, z *zip.Reader, entry string) (*Pattern, error) {
+	if entry == "" {
+		var entries []string
+		for _, f := range z.File {
+			if !f.FileInfo().IsDir() && isPatternName(f.Name) {
+				entries = append(entries, f.Name)
+			}
+		}
+		switch len(entries) {
+		case 0:
+			return nil, fmt.Errorf("%w: %s", ErrNoArchivePatterns, name)
+		case 1:
+			entry = entries[0]
+		default:
+			slices.Sort(entries)
+			for i, e := range entries {
+				entries[i] = name + archiveSep + e
+			}
+			return nil, MultiplePatternsError{URLs: entries}
+		}
+	}
+
+	f, err := z.Open(entry)
+	if err != nil {
+		return nil, fmt.Errorf("zip: %w", err)
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+	return unmarshalNamed(entry, limitReader(f))
+}
@@ -0,0 +1,179 @@
+package pattern
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:embed glider.rle.bz2
+var gliderRLEBzip2 []byte
+
+func gzipBytes(t *testing.T, b []byte) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write(b)
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func zipBytes(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	z := zip.NewWriter(&buf)
+	for name, b := range files {
+		w, err := z.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write(b)
+		require.NoError(t, err)
+	}
+	require.NoError(t, z.Close())
+	return buf.Bytes()
+}
+
+func writeFile(t *testing.T, name string, b []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, b, 0o600))
+	return path
+}
+
+func TestUnmarshalFile_compressed(t *testing.T) {
+	want, err := UnmarshalRLE(bytes.NewReader(gliderRLE))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"glider.rle.gz", gzipBytes(t, gliderRLE)},
+		{"glider.rle.bz2", gliderRLEBzip2},
+		{"glider.cells.gz", gzipBytes(t, gliderPlaintext)},
+		{"glider.gz", gzipBytes(t, gliderRLE)},
+		{"glider.rle.gz.gz", gzipBytes(t, gzipBytes(t, gliderRLE))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnmarshalFile(writeFile(t, tt.name, tt.b))
+			require.NoError(t, err)
+			assert.Equal(t, want.Tree.ToSlice(), got.Tree.ToSlice())
+		})
+	}
+
+	t.Run("detect", func(t *testing.T) {
+		for _, b := range [][]byte{gzipBytes(t, gliderRLE), gliderRLEBzip2} {
+			got, err := Unmarshal(bytes.NewReader(b))
+			require.NoError(t, err)
+			assert.Equal(t, want.Tree.ToSlice(), got.Tree.ToSlice())
+		}
+	})
+
+	t.Run("detect nested", func(t *testing.T) {
+		_, err := Unmarshal(bytes.NewReader(gzipBytes(t, gzipBytes(t, gliderRLE))))
+		require.ErrorIs(t, err, ErrDetectFailed)
+	})
+
+	t.Run("invalid gzip", func(t *testing.T) {
+		_, err := UnmarshalFile(writeFile(t, "glider.rle.gz", gliderRLE))
+		require.ErrorIs(t, err, gzip.ErrHeader)
+	})
+}
+
+func TestUnmarshalFile_zip(t *testing.T) {
+	want, err := UnmarshalRLE(bytes.NewReader(gliderRLE))
+	require.NoError(t, err)
+
+	t.Run("single", func(t *testing.T) {
+		path := writeFile(t, "glider.zip", zipBytes(t, map[string][]byte{
+			"README.txt":     []byte("hello"),
+			"dir/glider.rle": gliderRLE,
+			"dir/":           nil,
+		}))
+		got, err := UnmarshalFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, want.Tree.ToSlice(), got.Tree.ToSlice())
+	})
+
+	path := writeFile(t, "all.zip", zipBytes(t, map[string][]byte{
+		"glider.rle":          gliderRLE,
+		"glider.cells":        gliderPlaintext,
+		"compressed/b.rle.gz": gzipBytes(t, gliderRLE),
+	}))
+
+	t.Run("multiple", func(t *testing.T) {
+		_, err := UnmarshalFile(path)
+		var multiple MultiplePatternsError
+		require.ErrorAs(t, err, &multiple)
+		assert.Equal(t, []string{
+			path + "!/compressed/b.rle.gz",
+			path + "!/glider.cells",
+			path + "!/glider.rle",
+		}, multiple.URLs)
+
+		for _, entry := range multiple.URLs {
+			got, err := UnmarshalFile(entry)
+			require.NoError(t, err)
+			assert.Equal(t, want.Tree.ToSlice(), got.Tree.ToSlice())
+		}
+	})
+
+	t.Run("missing entry", func(t *testing.T) {
+		_, err := UnmarshalFile(path + "!/missing.rle")
+		require.Error(t, err)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		path := writeFile(t, "empty.zip", zipBytes(t, map[string][]byte{"README.txt": []byte("hello")}))
+		_, err := UnmarshalFile(path)
+		require.ErrorIs(t, err, ErrNoArchivePatterns)
+	})
+}
+
+func TestUnmarshalURL_archive(t *testing.T) {
+	want, err := UnmarshalRLE(bytes.NewReader(gliderRLE))
+	require.NoError(t, err)
+
+	archive := zipBytes(t, map[string][]byte{
+		"glider.rle":   gliderRLE,
+		"glider.cells": gliderPlaintext,
+	})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/all.zip":
+			_, _ = w.Write(archive)
+		case "/glider.rle.bz2":
+			_, _ = w.Write(gliderRLEBzip2)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	t.Run("bzip2", func(t *testing.T) {
+		got, err := UnmarshalURL(t.Context(), server.URL+"/glider.rle.bz2")
+		require.NoError(t, err)
+		assert.Equal(t, want.Tree.ToSlice(), got.Tree.ToSlice())
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		_, err := UnmarshalURL(t.Context(), server.URL+"/all.zip")
+		var multiple MultiplePatternsError
+		require.ErrorAs(t, err, &multiple)
+		assert.Equal(t, []string{
+			server.URL + "/all.zip!/glider.cells",
+			server.URL + "/all.zip!/glider.rle",
+		}, multiple.URLs)
+
+		got, err := UnmarshalURL(t.Context(), multiple.URLs[1])
+		require.NoError(t, err)
+		assert.Equal(t, want.Tree.ToSlice(), got.Tree.ToSlice())
+	})
+}
@@ -21,7 +21,7 @@ func (m MultiplePatternsError) Error() string {
 var ErrNoPatternLinks = errors.New("no pattern links found")
 
 func FindHrefPatterns(resp *http.Response) ([]string, error) {
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	doc, err := goquery.NewDocumentFromReader(limitReader(resp.Body))
 	if err != nil {
 		return nil, err
 	}
@@ -29,7 +29,7 @@ func FindHrefPatterns(resp *http.Response) ([]string, error) {
 	var matches []string
 	for _, s := range doc.Find("a").EachIter() {
 		if href, found := s.Attr("href"); found {
-			if slices.Contains(Extensions(), path.Ext(href)) {
+			if slices.Contains(Extensions(), FileExt(href)) {
 				matches = append(matches, href)
 			}
 		}
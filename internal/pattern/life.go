@@ -0,0 +1,269 @@
+package pattern
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"gabe565.com/cli-of-life/internal/quadtree"
+	"gabe565.com/cli-of-life/internal/rule"
+)
+
+const (
+	life105Header = "#Life 1.05"
+	life106Header = "#Life 1.06"
+)
+
+// UnmarshalLife reads a pattern in Life 1.05 or Life 1.06 format, depending on its header.
+func UnmarshalLife(r io.Reader) (*Pattern, error) {
+	br := bufio.NewReader(r)
+	header, err := br.Peek(len(life105Header))
+	if err != nil && len(header) == 0 {
+		return nil, fmt.Errorf("life: %w", err)
+	}
+	switch {
+	case bytes.Equal(header, []byte(life105Header)):
+		return UnmarshalLife105(br)
+	case bytes.Equal(header, []byte(life106Header)):
+		return UnmarshalLife106(br)
+	default:
+		return nil, fmt.Errorf("life: %w: %q", ErrInvalidHeader, header)
+	}
+}
+
+// UnmarshalLife105 reads a pattern in Life 1.05 format. Cells are listed in
+// blocks of "." and "*" rows, each starting at the offset given by a "#P" line.
+func UnmarshalLife105(r io.Reader) (*Pattern, error) {
+	pattern := Default()
+	scanner := newLineReader(r)
+	if !scanner.Scan() || !bytes.HasPrefix(scanner.Bytes(), []byte(life105Header)) {
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("life 1.05: %w", err)
+		}
+		return nil, fmt.Errorf("life 1.05: %w: %q", ErrInvalidHeader, scanner.Bytes())
+	}
+
+	builder := newBuilder()
+	var origin, p image.Point
+	for scanner.Scan() {
+		line := bytes.TrimRight(scanner.Bytes(), "\r")
+		switch {
+		case len(line) == 0:
+		case bytes.HasPrefix(line, []byte("#")):
+			switch {
+			case bytes.HasPrefix(line, []byte("#P")):
+				pos, col, err := parseLifePoint(line, 2)
+				if err != nil {
+					return nil, scanner.errorf("life 1.05", col, err)
+				}
+				if origin, err = builder.local(pos); err != nil {
+					return nil, scanner.errorf("life 1.05", col, err)
+				}
+				p = origin
+			case bytes.HasPrefix(line, []byte("#N")):
+				pattern.Rule = rule.GameOfLife()
+			case bytes.HasPrefix(line, []byte("#R")):
+				if err := pattern.Rule.UnmarshalText(bytes.TrimSpace(line[2:])); err != nil {
+					return nil, scanner.errorf("life 1.05", 0, err)
+				}
+			case bytes.HasPrefix(line, []byte("#D")):
+				comment := bytes.TrimSpace(line[2:])
+				if name, found := bytes.CutPrefix(comment, []byte("Name: ")); found {
+					pattern.Name = string(name)
+				} else if author, found := bytes.CutPrefix(comment, []byte("Author: ")); found {
+					pattern.Author = string(author)
+				} else {
+					if len(pattern.Comment) != 0 {
+						pattern.Comment += "\n"
+					}
+					pattern.Comment += string(comment)
+				}
+			}
+		default:
+			for i, b := range line {
+				switch b {
+				case '.':
+				case '*':
+					if err := builder.Set(p); err != nil {
+						return nil, scanner.errorf("life 1.05", i+1, err)
+					}
+				default:
+					return nil, scanner.errorf("life 1.05", i+1, fmt.Errorf("%w: %q", ErrUnexpectedCharacter, string(b)))
+				}
+				p.X++
+			}
+			p.X = origin.X
+			p.Y++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("life 1.05: %w", err)
+	}
+	builder.apply(pattern.Tree)
+	pattern.Tree.SetReset()
+	return pattern, nil
+}
+
+// UnmarshalLife106 reads a pattern in Life 1.06 format, which lists the
+// coordinates of each live cell.
+func UnmarshalLife106(r io.Reader) (*Pattern, error) {
+	pattern := Default()
+	scanner := newLineReader(r)
+	if !scanner.Scan() || !bytes.HasPrefix(scanner.Bytes(), []byte(life106Header)) {
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("life 1.06: %w", err)
+		}
+		return nil, fmt.Errorf("life 1.06: %w: %q", ErrInvalidHeader, scanner.Bytes())
+	}
+
+	builder := newBuilder()
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if trimmed := bytes.TrimSpace(line); len(trimmed) == 0 || trimmed[0] == '#' {
+			continue
+		}
+		pos, col, err := parseLifePoint(line, 0)
+		if err != nil {
+			return nil, scanner.errorf("life 1.06", col, err)
+		}
+		p, err := builder.local(pos)
+		if err != nil {
+			return nil, scanner.errorf("life 1.06", col, err)
+		}
+		if err := builder.Set(p); err != nil {
+			return nil, scanner.errorf("life 1.06", col, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("life 1.06: %w", err)
+	}
+	builder.apply(pattern.Tree)
+	pattern.Tree.SetReset()
+	return pattern, nil
+}
+
+// parseLifePoint parses a pair of space-separated coordinates like "-1 2",
+// starting at line[start]. Coordinates may be arbitrarily large. It returns
+// the 1-based column of the first invalid field, or 0 if the number of fields
+// is wrong.
+func parseLifePoint(line []byte, start int) (quadtree.BigPoint, int, error) {
+	fields := splitFields(line[start:])
+	if len(fields) != 2 {
+		return quadtree.BigPoint{}, 0, fmt.Errorf("%w: invalid coordinates: %q", ErrUnexpectedCharacter, line[start:])
+	}
+	var coords [2]*big.Int
+	for i, f := range fields {
+		var ok bool
+		if coords[i], ok = new(big.Int).SetString(f.text, 10); !ok {
+			return quadtree.BigPoint{}, start + f.col,
+				fmt.Errorf("%w: invalid coordinate: %q", ErrUnexpectedCharacter, f.text)
+		}
+	}
+	return quadtree.BigPoint{X: coords[0], Y: coords[1]}, start + fields[0].col, nil
+}
+
+// MarshalLife105 writes the pattern's current generation in Life 1.05 format.
+// Each group of consecutive non-empty rows is written as its own block.
+func MarshalLife105(w io.Writer, p *Pattern) error {
+	var buf bytes.Buffer
+	buf.WriteString(life105Header + "\n")
+	if p.Name != "" {
+		buf.WriteString("#D Name: " + p.Name + "\n")
+	}
+	if p.Author != "" {
+		buf.WriteString("#D Author: " + p.Author + "\n")
+	}
+	if p.Comment != "" {
+		for line := range strings.Lines(p.Comment) {
+			buf.WriteString("#D " + strings.TrimRight(line, "\r\n") + "\n")
+		}
+	}
+	if p.Rule.String() == rule.GameOfLife().String() {
+		buf.WriteString("#N\n")
+	} else {
+		buf.WriteString("#R " + lifeRule(p.Rule) + "\n")
+	}
+
+	snapshot := p.Tree.Snapshot()
+	offset := snapshot.Offset()
+	var block []quadtree.Run
+	writeBlock := func() {
+		if len(block) == 0 {
+			return
+		}
+		origin := image.Pt(block[0].X, block[0].Y)
+		for _, run := range block {
+			origin.X = min(origin.X, run.X)
+		}
+		pos := offset.Add(origin)
+		buf.WriteString("#P " + pos.X.String() + " " + pos.Y.String() + "\n")
+		x, y := origin.X, origin.Y
+		for _, run := range block {
+			if run.Y != y {
+				buf.WriteByte('\n')
+				x, y = origin.X, run.Y
+			}
+			buf.WriteString(strings.Repeat(".", run.X-x))
+			buf.WriteString(strings.Repeat("*", run.Len))
+			x = run.X + run.Len
+		}
+		buf.WriteByte('\n')
+		block = block[:0]
+	}
+	for run := range snapshot.Root().Runs() {
+		if len(block) != 0 && run.Y > block[len(block)-1].Y+1 {
+			writeBlock()
+		}
+		block = append(block, run)
+	}
+	writeBlock()
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// lifeRule formats a rule in the survival/birth notation used by Life 1.05.
+func lifeRule(r rule.Rule) string {
+	var buf strings.Builder
+	for _, v := range r.Survive {
+		buf.WriteString(strconv.Itoa(v))
+	}
+	buf.WriteByte('/')
+	for _, v := range r.Born {
+		buf.WriteString(strconv.Itoa(v))
+	}
+	return buf.String()
+}
+
+// MarshalLife writes the pattern's current generation in Life 1.06 format, or
+// in Life 1.05 if it has a name, author, comment or rule which Life 1.06 can't
+// store. Both versions use the .lif extension and are told apart by the header.
+func MarshalLife(w io.Writer, p *Pattern) error {
+	if p.Name != "" || p.Author != "" || p.Comment != "" || p.Rule.String() != rule.GameOfLife().String() {
+		return MarshalLife105(w, p)
+	}
+	return MarshalLife106(w, p)
+}
+
+// MarshalLife106 writes the pattern's current generation in Life 1.06 format.
+func MarshalLife106(w io.Writer, p *Pattern) error {
+	var buf bytes.Buffer
+	buf.WriteString(life106Header + "\n")
+	snapshot := p.Tree.Snapshot()
+	offset := snapshot.Offset()
+	for pt := range snapshot.Root().All() {
+		if offset.IsZero() {
+			buf.WriteString(strconv.Itoa(pt.X) + " " + strconv.Itoa(pt.Y) + "\n")
+			continue
+		}
+		pos := offset.Add(pt)
+		buf.WriteString(pos.X.String() + " " + pos.Y.String() + "\n")
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
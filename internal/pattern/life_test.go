@@ -0,0 +1,208 @@
+package pattern
+
+import (
+	"bytes"
+	"image"
+	"math/rand/v2"
+	"strings"
+	"testing"
+
+	"gabe565.com/cli-of-life/internal/quadtree"
+	"gabe565.com/cli-of-life/internal/rule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalLife(t *testing.T) {
+	glider := [][]int{{0, 1, 0}, {0, 0, 1}, {1, 1, 1}}
+
+	tests := []struct {
+		name     string
+		life     string
+		want     *Pattern
+		wantGrid [][]int
+		wantErr  require.ErrorAssertionFunc
+	}{
+		{
+			"life 1.05",
+			"#Life 1.05\n#D Name: Glider\n#D A comment\n#N\n#P -1 -1\n.*\n..*\n***\n",
+			&Pattern{Name: "Glider", Comment: "A comment", Rule: rule.GameOfLife()},
+			glider,
+			require.NoError,
+		},
+		{
+			"life 1.05 rule",
+			"#Life 1.05\r\n#R 23/36\r\n#P 0 0\r\n*\r\n",
+			&Pattern{Rule: rule.HighLife()},
+			[][]int{{1}},
+			require.NoError,
+		},
+		{
+			"life 1.05 blocks",
+			"#Life 1.05\n#P 0 0\n*\n#P 4 2\n.*\n",
+			&Pattern{Rule: rule.GameOfLife()},
+			[][]int{{1, 0, 0, 0, 0, 0}, {0, 0, 0, 0, 0, 0}, {0, 0, 0, 0, 0, 1}},
+			require.NoError,
+		},
+		{
+			"life 1.06",
+			"#Life 1.06\n0 -1\n1 0\n-1 1\n0 1\n1 1\n",
+			&Pattern{Rule: rule.GameOfLife()},
+			glider,
+			require.NoError,
+		},
+		{
+			"life 1.05 invalid character",
+			"#Life 1.05\n#P 0 0\n.o\n",
+			nil,
+			nil,
+			require.Error,
+		},
+		{
+			"life 1.05 invalid block",
+			"#Life 1.05\n#P 0\n*\n",
+			nil,
+			nil,
+			require.Error,
+		},
+		{
+			"life 1.06 invalid coordinates",
+			"#Life 1.06\n0 a\n",
+			nil,
+			nil,
+			require.Error,
+		},
+		{
+			"invalid header",
+			"#Life 1.07\n",
+			nil,
+			nil,
+			require.Error,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnmarshalLife(strings.NewReader(tt.life))
+			tt.wantErr(t, err)
+			if tt.want != nil {
+				require.NotNil(t, got)
+				assert.Equal(t, tt.want.Name, got.Name)
+				assert.Equal(t, tt.want.Comment, got.Comment)
+				assert.Equal(t, tt.want.Rule, got.Rule)
+				assert.Equal(t, tt.wantGrid, got.Tree.ToSlice())
+			}
+		})
+	}
+
+	t.Run("detect", func(t *testing.T) {
+		for _, life := range []string{tests[0].life, tests[3].life} {
+			got, err := Unmarshal(strings.NewReader(life))
+			require.NoError(t, err)
+			assert.Equal(t, glider, got.Tree.ToSlice())
+		}
+	})
+}
+
+func TestMarshalLife105(t *testing.T) {
+	p := Default()
+	require.NoError(t, p.Tree.Set(image.Pt(-2, -1), 1))
+	require.NoError(t, p.Tree.Set(image.Pt(-1, -1), 1))
+	require.NoError(t, p.Tree.Set(image.Pt(0, 0), 1))
+	require.NoError(t, p.Tree.Set(image.Pt(5, 3), 1))
+
+	var buf bytes.Buffer
+	require.NoError(t, MarshalLife105(&buf, p))
+	assert.Equal(t, "#Life 1.05\n#N\n#P -2 -1\n**\n..*\n#P 5 3\n*\n", buf.String())
+
+	t.Run("round trip", func(t *testing.T) {
+		p := Default()
+		p.Name = "Random"
+		p.Author = "Test"
+		p.Comment = "First line\nSecond line"
+		p.Rule = rule.HighLife()
+		r := rand.New(rand.NewPCG(1, 2)) //nolint:gosec
+		for range 2000 {
+			require.NoError(t, p.Tree.Set(image.Pt(r.IntN(200)-100, r.IntN(150)-50), 1))
+		}
+
+		var buf bytes.Buffer
+		require.NoError(t, MarshalLife105(&buf, p))
+
+		got, err := UnmarshalLife(&buf)
+		require.NoError(t, err)
+		assert.Equal(t, p.Name, got.Name)
+		assert.Equal(t, p.Author, got.Author)
+		assert.Equal(t, p.Comment, got.Comment)
+		assert.Equal(t, p.Rule, got.Rule)
+		assert.Equal(t, p.Tree.FilledCoords(), got.Tree.FilledCoords())
+		assert.Equal(t, p.Tree.ToSlice(), got.Tree.ToSlice())
+	})
+}
+
+func TestMarshalLife106(t *testing.T) {
+	p := Default()
+	require.NoError(t, p.Tree.Set(image.Pt(-2, -1), 1))
+	require.NoError(t, p.Tree.Set(image.Pt(3, 2), 1))
+
+	var buf bytes.Buffer
+	require.NoError(t, MarshalLife106(&buf, p))
+	assert.Equal(t, "#Life 1.06\n-2 -1\n3 2\n", buf.String())
+
+	got, err := UnmarshalLife(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, p.Tree.FilledCoords(), got.Tree.FilledCoords())
+	assert.Equal(t, p.Tree.ToSlice(), got.Tree.ToSlice())
+}
+
+func TestMarshalLife_bigPosition(t *testing.T) {
+	t.Run("life 1.05", func(t *testing.T) {
+		const life = "#Life 1.05\n#N\n#P -100000000000000000000 7\n**\n"
+		p, err := UnmarshalLife(strings.NewReader(life))
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		require.NoError(t, MarshalLife105(&buf, p))
+		assert.Equal(t, life, buf.String())
+	})
+
+	t.Run("life 1.06", func(t *testing.T) {
+		const life = "#Life 1.06\n100000000000000000000 -3\n100000000000000000001 -3\n"
+		p, err := UnmarshalLife(strings.NewReader(life))
+		require.NoError(t, err)
+		assert.Equal(t, image.Rect(0, 0, 2, 1), p.Tree.FilledCoords())
+		var buf bytes.Buffer
+		require.NoError(t, MarshalLife106(&buf, p))
+		assert.Equal(t, life, buf.String())
+	})
+}
+
+func TestMarshalLife(t *testing.T) {
+	tests := []struct {
+		name    string
+		p       *Pattern
+		wantHdr string
+	}{
+		{"cells only", &Pattern{Rule: rule.GameOfLife()}, life106Header},
+		{"name", &Pattern{Name: "Glider", Rule: rule.GameOfLife()}, life105Header},
+		{"author", &Pattern{Author: "Richard K. Guy", Rule: rule.GameOfLife()}, life105Header},
+		{"comment", &Pattern{Comment: "A comment", Rule: rule.GameOfLife()}, life105Header},
+		{"rule", &Pattern{Rule: rule.HighLife()}, life105Header},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.p.Tree = quadtree.New()
+			require.NoError(t, tt.p.Tree.Set(image.Pt(1, 2), 1))
+
+			var buf bytes.Buffer
+			require.NoError(t, MarshalLife(&buf, tt.p))
+			assert.True(t, strings.HasPrefix(buf.String(), tt.wantHdr+"\n"), buf.String())
+
+			got, err := UnmarshalLife(&buf)
+			require.NoError(t, err)
+			assert.Equal(t, tt.p.Name, got.Name)
+			assert.Equal(t, tt.p.Author, got.Author)
+			assert.Equal(t, tt.p.Comment, got.Comment)
+			assert.Equal(t, tt.p.Rule.String(), got.Rule.String())
+			assert.Equal(t, tt.p.Tree.ToSlice(), got.Tree.ToSlice())
+		})
+	}
+}
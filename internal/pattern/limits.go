@@ -0,0 +1,164 @@
+package pattern
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"sync/atomic"
+
+	"gabe565.com/cli-of-life/internal/quadtree"
+)
+
+// Limits bound the patterns which will be loaded, so that hostile or malformed
+// files fail quickly instead of exhausting memory. A zero value disables a limit.
+type Limits struct {
+	// Bytes is the largest input which will be read, after decompression.
+	Bytes int64
+	// Size is the largest width or height of a pattern.
+	Size int
+	// Cells is the largest number of live cells in a pattern.
+	Cells int
+	// LenientHeaders loads RLE patterns whose cells do not fit in the header's
+	// width and height, which some older programs write. It logs a warning
+	// instead of returning an error.
+	LenientHeaders bool
+}
+
+//nolint:gochecknoglobals
+var limits atomic.Pointer[Limits]
+
+// SetLimits changes the limits used when loading patterns. Loads which have
+// already started keep the limits they started with.
+func SetLimits(l Limits) {
+	limits.Store(&l)
+}
+
+// currentLimits returns the limits set by SetLimits. Parsers load them once,
+// so a concurrent SetLimits can't change them partway through a pattern.
+func currentLimits() Limits {
+	if l := limits.Load(); l != nil {
+		return *l
+	}
+	return Limits{}
+}
+
+var ErrLimitExceeded = errors.New("pattern exceeds limit")
+
+// checkSize returns an error if a width or height exceeds the size limit.
+func (l Limits) checkSize(size image.Point) error {
+	if l.Size != 0 && (size.X > l.Size || size.Y > l.Size) {
+		return fmt.Errorf("%w: %dx%d is larger than %dx%d",
+			ErrLimitExceeded, size.X, size.Y, l.Size, l.Size)
+	}
+	return nil
+}
+
+// checkCells returns an error if a population exceeds the cell limit.
+func (l Limits) checkCells(n int) error {
+	if l.Cells != 0 && n > l.Cells {
+		return fmt.Errorf("%w: more than %d cells", ErrLimitExceeded, l.Cells)
+	}
+	return nil
+}
+
+// limitReader returns a reader which fails once more than the byte limit has
+// been read, rather than silently truncating the input.
+func limitReader(r io.Reader) io.Reader {
+	l := currentLimits()
+	if l.Bytes == 0 {
+		return r
+	}
+	return &limitedReader{r: r, n: l.Bytes, limit: l.Bytes}
+}
+
+type limitedReader struct {
+	r     io.Reader
+	n     int64
+	limit int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		// Only fail if there is more input.
+		var b [1]byte
+		if n, err := l.r.Read(b[:]); n == 0 {
+			return 0, err
+		}
+		return 0, fmt.Errorf("%w: more than %d bytes", ErrLimitExceeded, l.limit)
+	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// builder wraps quadtree.Builder, enforcing the size and cell limits.
+type builder struct {
+	*quadtree.Builder
+	limits Limits
+	cells  int
+	bounds image.Rectangle
+	// offset is the absolute position of the builder's origin. It is fixed by
+	// the first cell or position, so patterns far beyond the range of an int
+	// are built near the origin.
+	offset quadtree.BigPoint
+	placed bool
+}
+
+func newBuilder() *builder {
+	return &builder{Builder: quadtree.NewBuilder(), limits: currentLimits()}
+}
+
+// local converts an absolute position to one relative to the builder's
+// offset. The first call fixes the offset, unless a cell has already been set.
+func (b *builder) local(p quadtree.BigPoint) (image.Point, error) {
+	if !b.placed {
+		b.placed = true
+		if pt, ok := p.Point(); !ok || !quadtree.InBounds(pt) {
+			b.offset = p
+		}
+	}
+	pt, ok := p.Sub(b.offset).Point()
+	if !ok || !quadtree.InBounds(pt) {
+		return image.Point{}, fmt.Errorf("%w: %s", quadtree.ErrUniverseOverflow, p)
+	}
+	return pt, nil
+}
+
+// apply replaces the cells of g with the built pattern at its absolute position.
+func (b *builder) apply(g *quadtree.Gosper) {
+	g.SetCells(b.Node())
+	g.SetOffset(b.offset)
+}
+
+// Set marks the cell at p as alive.
+func (b *builder) Set(p image.Point) error {
+	return b.SetRun(p, 1)
+}
+
+// SetRun marks n consecutive cells starting at p and extending to the right as alive.
+func (b *builder) SetRun(p image.Point, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	b.placed = true
+	if !quadtree.RunInBounds(p, n) {
+		return fmt.Errorf("%w: %s", quadtree.ErrUniverseOverflow, p)
+	}
+	if err := b.limits.checkCells(b.cells + n); err != nil {
+		return err
+	}
+	bounds := b.bounds.Union(image.Rect(p.X, p.Y, p.X+n, p.Y+1))
+	if err := b.limits.checkSize(bounds.Size()); err != nil {
+		return err
+	}
+	if err := b.Builder.SetRun(p, n); err != nil {
+		return err
+	}
+	b.cells += n
+	b.bounds = bounds
+	return nil
+}
@@ -0,0 +1,182 @@
+package pattern
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setLimits(t *testing.T, l Limits) {
+	prev := currentLimits()
+	SetLimits(l)
+	t.Cleanup(func() {
+		SetLimits(prev)
+	})
+}
+
+func TestLimits(t *testing.T) {
+	setLimits(t, Limits{Bytes: 1024, Size: 100, Cells: 50})
+
+	tests := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{"header size", "x = 999999999999, y = 1\no!\n", ErrLimitExceeded},
+		{"content size", "x = 1, y = 1\no200bo!\n", ErrLimitExceeded},
+		{"cells", "x = 60, y = 1\n60o!\n", ErrLimitExceeded},
+		{"bytes", "x = 1, y = 1\n" + strings.Repeat("#C comment\n", 100) + "o!\n", ErrLimitExceeded},
+		{"run count", "x = 1, y = 1\n99999999999999999999b!\n", nil},
+		{"plaintext cells", strings.Repeat("O", 60) + "\n", ErrLimitExceeded},
+		{"life 1.06 size", "#Life 1.06\n0 0\n1000 0\n", ErrLimitExceeded},
+		{"life 1.05 offset", "#Life 1.05\n#P 0 0\n*\n#P 9223372036854775807 0\n*\n", nil},
+		{"apgcode", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.input == "" {
+				_, err := UnmarshalAPGCode("xs64_" + strings.Repeat("v", 13))
+				require.ErrorIs(t, err, ErrLimitExceeded)
+				return
+			}
+			_, err := Unmarshal(strings.NewReader(tt.input))
+			require.Error(t, err)
+			if tt.wantErr != nil {
+				require.ErrorIs(t, err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("macrocell population", func(t *testing.T) {
+		// Each level doubles the population in both directions.
+		var buf bytes.Buffer
+		buf.WriteString("[M2]\n" + strings.Repeat("*******$", 8) + "\n")
+		for level := 4; level < 10; level++ {
+			i := strconv.Itoa(level - 3)
+			buf.WriteString(strconv.Itoa(level) + " " + i + " " + i + " " + i + " " + i + "\n")
+		}
+		_, err := UnmarshalMacrocell(&buf)
+		require.ErrorIs(t, err, ErrLimitExceeded)
+	})
+
+	t.Run("exact byte limit", func(t *testing.T) {
+		setLimits(t, Limits{Bytes: 5})
+		got, err := io.ReadAll(limitReader(strings.NewReader("12345")))
+		require.NoError(t, err)
+		assert.Equal(t, "12345", string(got))
+
+		_, err = io.ReadAll(limitReader(strings.NewReader("123456")))
+		require.ErrorIs(t, err, ErrLimitExceeded)
+	})
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    ParseError
+		wantMsg string
+	}{
+		{
+			"plaintext",
+			"!Name: Test\n.O.\n..X\n",
+			ParseError{Format: "plaintext", Line: 3, Column: 3, Err: ErrUnexpectedCharacter},
+			`plaintext: line 3, column 3: unexpected character: "X"`,
+		},
+		{
+			"rle header",
+			"#N Test\nx = 1, y = -1\no!\n",
+			ParseError{Format: "rle", Line: 2, Err: ErrInvalidHeader},
+			`rle: line 2: invalid header: y = "-1"`,
+		},
+		{
+			"rle multi-state",
+			"x = 2, y = 1\nAB!\n",
+			ParseError{Format: "rle", Line: 2, Column: 1, Err: ErrMultiState},
+			`rle: line 2, column 1: multi-state patterns are not supported: "A"`,
+		},
+		{
+			"life 1.05",
+			"#Life 1.05\n#P 0 0\n*.o\n",
+			ParseError{Format: "life 1.05", Line: 3, Column: 3, Err: ErrUnexpectedCharacter},
+			`life 1.05: line 3, column 3: unexpected character: "o"`,
+		},
+		{
+			"macrocell",
+			"[M2]\n.*$\n4 1 0 0 9\n",
+			ParseError{Format: "macrocell", Line: 3, Column: 9, Err: ErrInvalidNode},
+			`macrocell: line 3, column 9: invalid node: child "9" in "4 1 0 0 9"`,
+		},
+		{
+			"macrocell leaf",
+			"[M2]\n.*$\n..o\n",
+			ParseError{Format: "macrocell", Line: 3, Column: 3, Err: ErrUnexpectedCharacter},
+			`macrocell: line 3, column 3: unexpected character: "o" in leaf: "..o"`,
+		},
+		{
+			"life 1.06",
+			"#Life 1.06\n0 0\n 1 y\n",
+			ParseError{Format: "life 1.06", Line: 3, Column: 4, Err: ErrUnexpectedCharacter},
+			`life 1.06: line 3, column 4: unexpected character: invalid coordinate: "y"`,
+		},
+		{
+			"rle header mismatch",
+			"x = 1, y = 1\n2o!\n",
+			ParseError{Format: "rle", Line: 2, Column: 3, Err: ErrHeaderMismatch},
+			"rle: line 2, column 3: content does not match header size: header is (1,1), content is (2,1)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Unmarshal(strings.NewReader(tt.input))
+			var parseErr *ParseError
+			require.ErrorAs(t, err, &parseErr)
+			assert.Equal(t, tt.want.Format, parseErr.Format)
+			assert.Equal(t, tt.want.Line, parseErr.Line)
+			assert.Equal(t, tt.want.Column, parseErr.Column)
+			assert.True(t, errors.Is(err, tt.want.Err))
+			assert.Equal(t, tt.wantMsg, err.Error())
+		})
+	}
+}
+
+func TestUnmarshalRLE_lenientHeaders(t *testing.T) {
+	setLimits(t, Limits{LenientHeaders: true})
+	got, err := UnmarshalRLE(strings.NewReader("x = 1, y = 0\no!"))
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{{1}}, got.Tree.ToSlice())
+}
+
+func TestSetLimits_concurrent(t *testing.T) {
+	prev := currentLimits()
+	t.Cleanup(func() {
+		SetLimits(prev)
+	})
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Go(func() {
+			SetLimits(Limits{Cells: i + 1})
+		})
+		wg.Go(func() {
+			_, _ = UnmarshalRLE(strings.NewReader("x = 2, y = 1\n2o!"))
+		})
+	}
+	wg.Wait()
+}
+
+func TestUnmarshalRLE_longLine(t *testing.T) {
+	// bufio.Scanner fails on lines longer than 64 KiB.
+	const n = 40_000
+	rle := "x = " + strconv.Itoa(2*n) + ", y = 1\n" + strings.Repeat("bo", n) + "!\n"
+	got, err := UnmarshalRLE(strings.NewReader(rle))
+	require.NoError(t, err)
+	assert.Equal(t, n, got.Tree.Stats().Population)
+}
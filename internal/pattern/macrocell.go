@@ -0,0 +1,281 @@
+package pattern
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"gabe565.com/cli-of-life/internal/quadtree"
+)
+
+const macrocellHeader = "[M2]"
+
+var (
+	ErrInvalidNode     = errors.New("invalid node")
+	ErrMultiStateNodes = errors.New("multi-state nodes are not supported")
+)
+
+// UnmarshalMacrocell reads a pattern in Golly's Macrocell format. Each node
+// line maps directly onto a shared quadtree node, so patterns with repeated
+// structure load without expanding them into cells.
+func UnmarshalMacrocell(r io.Reader) (*Pattern, error) {
+	pattern := Default()
+	limits := currentLimits()
+	scanner := newLineReader(r)
+	if !scanner.Scan() || !bytes.HasPrefix(scanner.Bytes(), []byte(macrocellHeader)) {
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("macrocell: %w", err)
+		}
+		return nil, fmt.Errorf("macrocell: %w: %q", ErrInvalidHeader, scanner.Bytes())
+	}
+
+	// Index 0 refers to an empty node, so node numbers start at 1.
+	nodes := []*quadtree.Node{nil}
+	var gen *big.Int
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		switch {
+		case len(line) == 0:
+		case bytes.HasPrefix(line, []byte("#")):
+			if r, found := bytes.CutPrefix(line, []byte("#R ")); found {
+				if err := pattern.Rule.UnmarshalText(bytes.TrimSpace(r)); err != nil {
+					return nil, scanner.errorf("macrocell", 0, err)
+				}
+			} else if g, found := bytes.CutPrefix(line, []byte("#G ")); found {
+				var ok bool
+				if gen, ok = new(big.Int).SetString(string(bytes.TrimSpace(g)), 10); !ok || gen.Sign() < 0 {
+					return nil, scanner.errorf("macrocell", 0, fmt.Errorf("%w: %q", ErrInvalidHeader, line))
+				}
+			} else if name, found := bytes.CutPrefix(line, []byte("#N ")); found {
+				pattern.Name = string(bytes.TrimSpace(name))
+			} else if author, found := bytes.CutPrefix(line, []byte("#O ")); found {
+				pattern.Author = string(bytes.TrimSpace(author))
+			} else if comment, found := bytes.CutPrefix(line, []byte("#C")); found {
+				if len(pattern.Comment) != 0 {
+					pattern.Comment += "\n"
+				}
+				pattern.Comment += string(bytes.TrimSpace(comment))
+			}
+		case line[0] == '.' || line[0] == '*' || line[0] == '$':
+			bits, col, err := parseMacrocellLeaf(line)
+			if err != nil {
+				return nil, scanner.errorf("macrocell", col, err)
+			}
+			nodes = append(nodes, quadtree.Leaf(bits))
+		default:
+			n, col, err := parseMacrocellNode(line, nodes)
+			if err != nil {
+				return nil, scanner.errorf("macrocell", col, err)
+			}
+			// Shared nodes can describe huge populations in a few lines.
+			if err := limits.checkCells(n.Value()); err != nil {
+				return nil, scanner.errorf("macrocell", 0, err)
+			}
+			nodes = append(nodes, n)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("macrocell: %w", err)
+	}
+
+	if len(nodes) > 1 {
+		root := nodes[len(nodes)-1]
+		if err := limits.checkSize(root.FilledCoords().Size()); err != nil {
+			return nil, fmt.Errorf("macrocell: %w", err)
+		}
+		pattern.Tree.SetCells(root)
+	}
+	if gen != nil {
+		pattern.Tree.SetGeneration(gen)
+	}
+	pattern.Tree.SetReset()
+	return pattern, nil
+}
+
+// parseMacrocellLeaf parses an 8x8 leaf, where "." is a dead cell, "*" is a
+// live cell, and "$" ends a row. Errors include the 1-based column of the
+// invalid byte.
+func parseMacrocellLeaf(line []byte) (uint64, int, error) {
+	var bits uint64
+	var x, y int
+	for i, b := range line {
+		switch b {
+		case '.', '*':
+			if x >= 8 || y >= 8 {
+				return 0, i + 1, fmt.Errorf("%w: leaf exceeds 8x8: %q", ErrInvalidNode, line)
+			}
+			if b == '*' {
+				bits |= 1 << (y*8 + x)
+			}
+			x++
+		case '$':
+			x = 0
+			y++
+		default:
+			return 0, i + 1, fmt.Errorf("%w: %q in leaf: %q", ErrUnexpectedCharacter, string(b), line)
+		}
+	}
+	return bits, 0, nil
+}
+
+// parseMacrocellNode parses a line like "4 1 0 2 3", which has the node's
+// level followed by the numbers of its NW, NE, SW and SE children. Errors
+// include the 1-based column of the invalid field, or 0 for the whole line.
+func parseMacrocellNode(line []byte, nodes []*quadtree.Node) (*quadtree.Node, int, error) {
+	fields := splitFields(line)
+	if len(fields) != 5 {
+		return nil, 0, fmt.Errorf("%w: %q", ErrInvalidNode, line)
+	}
+	level, err := strconv.Atoi(fields[0].text)
+	if err != nil {
+		return nil, fields[0].col, fmt.Errorf("%w: %q", ErrInvalidNode, line)
+	}
+	switch {
+	case level <= quadtree.LeafLevel:
+		return nil, fields[0].col, fmt.Errorf("%w: level %d", ErrMultiStateNodes, level)
+	case level > quadtree.MaxLevel:
+		return nil, fields[0].col, fmt.Errorf("%w: level %d", quadtree.ErrUniverseOverflow, level)
+	}
+
+	var children [4]*quadtree.Node
+	for i, f := range fields[1:] {
+		idx, err := strconv.Atoi(f.text)
+		if err != nil || idx < 0 || idx >= len(nodes) {
+			return nil, f.col, fmt.Errorf("%w: child %q in %q", ErrInvalidNode, f.text, line)
+		}
+		if idx == 0 {
+			children[i] = quadtree.Empty(uint8(level - 1)) //nolint:gosec
+		} else {
+			children[i] = nodes[idx]
+		}
+	}
+	n, err := quadtree.Join(quadtree.Children{NW: children[0], NE: children[1], SW: children[2], SE: children[3]})
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w: %w", ErrInvalidNode, err)
+	}
+	if int(n.Level()) != level {
+		return nil, 0, fmt.Errorf("%w: children of %q have level %d", ErrInvalidNode, line, n.Level()-1)
+	}
+	return n, 0, nil
+}
+
+// MarshalMacrocell writes the pattern's current generation in Golly's
+// Macrocell format. Identical subtrees are written once.
+func MarshalMacrocell(w io.Writer, p *Pattern) error {
+	var buf bytes.Buffer
+	buf.WriteString(macrocellHeader + " (cli-of-life)\n")
+	buf.WriteString("#R " + p.Rule.String() + "\n")
+	snapshot := p.Tree.Snapshot()
+	if gen := snapshot.Generation(); gen.Sign() != 0 {
+		buf.WriteString("#G " + gen.String() + "\n")
+	}
+	if p.Name != "" {
+		buf.WriteString("#N " + p.Name + "\n")
+	}
+	if p.Author != "" {
+		buf.WriteString("#O " + p.Author + "\n")
+	}
+	if p.Comment != "" {
+		for line := range strings.Lines(p.Comment) {
+			buf.WriteString("#C " + strings.TrimRight(line, "\r\n") + "\n")
+		}
+	}
+
+	root := snapshot.Root()
+	if !root.IsEmpty() {
+		enc := macrocellEncoder{
+			buf:    &buf,
+			ptrs:   make(map[*quadtree.Node]int),
+			leaves: make(map[uint64]int),
+			nodes:  make(map[macrocellKey]int),
+		}
+		enc.write(root)
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+type macrocellKey struct {
+	level          uint8
+	nw, ne, sw, se int
+}
+
+// macrocellEncoder numbers nodes as they are written. Nodes are looked up by
+// their address first, so each shared subtree is only visited once, then by
+// their contents, since large nodes are not always shared in memory.
+type macrocellEncoder struct {
+	buf    *bytes.Buffer
+	ptrs   map[*quadtree.Node]int
+	leaves map[uint64]int
+	nodes  map[macrocellKey]int
+	count  int
+}
+
+// write writes n after its children, and returns its number.
+func (e *macrocellEncoder) write(n *quadtree.Node) int {
+	if n.IsEmpty() {
+		return 0
+	}
+	if i, ok := e.ptrs[n]; ok {
+		return i
+	}
+	i := e.number(n)
+	e.ptrs[n] = i
+	return i
+}
+
+// number returns the number of n, writing it if an identical node has not
+// been written yet.
+func (e *macrocellEncoder) number(n *quadtree.Node) int {
+	if n.Level() == quadtree.LeafLevel {
+		bits := n.Bits()
+		if i, ok := e.leaves[bits]; ok {
+			return i
+		}
+		writeMacrocellLeaf(e.buf, bits)
+		e.count++
+		e.leaves[bits] = e.count
+		return e.count
+	}
+
+	key := macrocellKey{
+		level: n.Level(),
+		nw:    e.write(n.NW),
+		ne:    e.write(n.NE),
+		sw:    e.write(n.SW),
+		se:    e.write(n.SE),
+	}
+	if i, ok := e.nodes[key]; ok {
+		return i
+	}
+	e.buf.WriteString(strconv.Itoa(int(key.level)) + " " + strconv.Itoa(key.nw) + " " + strconv.Itoa(key.ne) + " " +
+		strconv.Itoa(key.sw) + " " + strconv.Itoa(key.se) + "\n")
+	e.count++
+	e.nodes[key] = e.count
+	return e.count
+}
+
+// writeMacrocellLeaf writes each row up to its last live cell, omitting
+// trailing empty rows.
+func writeMacrocellLeaf(buf *bytes.Buffer, bits uint64) {
+	for y := range 8 {
+		if bits>>(y*8) == 0 {
+			break
+		}
+		row := byte(bits >> (y * 8))
+		for x := 0; row>>x != 0; x++ {
+			if row&(1<<x) != 0 {
+				buf.WriteByte('*')
+			} else {
+				buf.WriteByte('.')
+			}
+		}
+		buf.WriteByte('$')
+	}
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,147 @@
+package pattern
+
+import (
+	"bytes"
+	"image"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gabe565.com/cli-of-life/internal/quadtree"
+	"gabe565.com/cli-of-life/internal/rule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnmarshalMacrocell(t *testing.T) {
+	t.Run("glider", func(t *testing.T) {
+		const mc = "[M2] (golly 4.3)\n#R B36/S23\n#N Glider\n#C A comment\n.*$..*$***$\n4 1 0 0 0\n"
+		got, err := UnmarshalMacrocell(strings.NewReader(mc))
+		require.NoError(t, err)
+		assert.Equal(t, "Glider", got.Name)
+		assert.Equal(t, "A comment", got.Comment)
+		assert.Equal(t, rule.HighLife(), got.Rule)
+		assert.Equal(t, [][]int{{0, 1, 0}, {0, 0, 1}, {1, 1, 1}}, got.Tree.ToSlice())
+		assert.True(t, got.Tree.Get(image.Pt(-7, -8)))
+	})
+
+	tests := []struct {
+		name    string
+		mc      string
+		wantErr error
+	}{
+		{"bad header", "x = 1, y = 1\n", ErrInvalidHeader},
+		{"bad child", "[M2]\n.*$\n4 1 0 0 2\n", ErrInvalidNode},
+		{"level mismatch", "[M2]\n.*$\n4 1 0 0 0\n6 2 0 0 0\n", ErrInvalidNode},
+		{"multi-state", "[M2]\n1 1 0 0 0\n", ErrMultiStateNodes},
+		{"leaf overflow", "[M2]\n.........*$\n", ErrInvalidNode},
+		{"bad character", "[M2]\n.o$\n", ErrUnexpectedCharacter},
+		{"bad generation", "[M2]\n#G -1\n", ErrInvalidHeader},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := UnmarshalMacrocell(strings.NewReader(tt.mc))
+			require.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestMarshalMacrocell(t *testing.T) {
+	t.Run("shared", func(t *testing.T) {
+		p := Default()
+		for y := range 4 {
+			for x := range 4 {
+				require.NoError(t, p.Tree.Set(image.Pt(x*64, y*64), 1))
+				require.NoError(t, p.Tree.Set(image.Pt(x*64+1, y*64), 1))
+			}
+		}
+
+		var buf bytes.Buffer
+		require.NoError(t, MarshalMacrocell(&buf, p))
+		assert.True(t, strings.HasPrefix(buf.String(), macrocellHeader))
+		// One leaf, then one node per level, shared across all 16 blocks.
+		assert.Contains(t, buf.String(), "\n**$\n4 1 0 0 0\n")
+		assert.Less(t, strings.Count(buf.String(), "\n"), 30)
+
+		got, err := UnmarshalMacrocell(&buf)
+		require.NoError(t, err)
+		assert.Equal(t, p.Tree.FilledCoords(), got.Tree.FilledCoords())
+		assert.Equal(t, p.Tree.ToSlice(), got.Tree.ToSlice())
+	})
+
+	t.Run("deeply shared", func(t *testing.T) {
+		// Each level has four identical children, so the tree holds 4^15 cells.
+		var mc strings.Builder
+		mc.WriteString(macrocellHeader + "\n*$\n")
+		for level := 4; level <= 18; level++ {
+			i := strconv.Itoa(level - 3)
+			mc.WriteString(strconv.Itoa(level) + " " + i + " " + i + " " + i + " " + i + "\n")
+		}
+		p, err := UnmarshalMacrocell(strings.NewReader(mc.String()))
+		require.NoError(t, err)
+
+		var buf bytes.Buffer
+		require.NoError(t, MarshalMacrocell(&buf, p))
+		assert.Less(t, strings.Count(buf.String(), "\n"), 30)
+
+		got, err := UnmarshalMacrocell(&buf)
+		require.NoError(t, err)
+		assert.Equal(t, 1<<30, got.Tree.Snapshot().Root().Value())
+		assert.Equal(t, p.Tree.FilledCoords(), got.Tree.FilledCoords())
+	})
+
+	t.Run("random", func(t *testing.T) {
+		p := Default()
+		p.Name = "Random"
+		p.Author = "Test"
+		p.Comment = "First line\nSecond line"
+		p.Rule = rule.HighLife()
+		r := rand.New(rand.NewPCG(1, 2)) //nolint:gosec
+		for range 2000 {
+			require.NoError(t, p.Tree.Set(image.Pt(r.IntN(200)-100, r.IntN(150)-50), 1))
+		}
+
+		var buf bytes.Buffer
+		require.NoError(t, MarshalMacrocell(&buf, p))
+
+		got, err := Unmarshal(&buf)
+		require.NoError(t, err)
+		assert.Equal(t, p.Name, got.Name)
+		assert.Equal(t, p.Author, got.Author)
+		assert.Equal(t, p.Comment, got.Comment)
+		assert.Equal(t, p.Rule, got.Rule)
+		assert.Equal(t, p.Tree.FilledCoords(), got.Tree.FilledCoords())
+		assert.Equal(t, p.Tree.ToSlice(), got.Tree.ToSlice())
+	})
+
+	t.Run("generation", func(t *testing.T) {
+		glider, err := UnmarshalRLE(bytes.NewReader(gliderRLE))
+		require.NoError(t, err)
+		require.NoError(t, glider.Step(t.Context(), 5, nil))
+
+		var buf bytes.Buffer
+		require.NoError(t, MarshalMacrocell(&buf, glider))
+		assert.Contains(t, buf.String(), "\n#G 5\n")
+
+		got, err := UnmarshalMacrocell(&buf)
+		require.NoError(t, err)
+		assert.Equal(t, glider.Tree.Stats().Generation, got.Tree.Stats().Generation)
+		assert.Equal(t, slices.Collect(glider.Tree.All()), slices.Collect(got.Tree.All()))
+
+		got.Tree.Reset()
+		assert.Equal(t, glider.Tree.Stats().Generation, got.Tree.Stats().Generation)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, MarshalMacrocell(&buf, Default()))
+		assert.Equal(t, "[M2] (cli-of-life)\n#R B3/S23\n", buf.String())
+
+		got, err := UnmarshalMacrocell(&buf)
+		require.NoError(t, err)
+		assert.True(t, got.Tree.IsEmpty())
+		assert.Equal(t, quadtree.New().Level(), got.Tree.Level())
+	})
+}
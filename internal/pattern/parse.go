@@ -0,0 +1,115 @@
+package pattern
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+)
+
+// ParseError describes where a pattern file is malformed.
+type ParseError struct {
+	Format string
+	Line   int
+	// Column is the 1-based byte offset in the line, or 0 if the whole line is invalid.
+	Column int
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	s := e.Format + ": line " + strconv.Itoa(e.Line)
+	if e.Column != 0 {
+		s += ", column " + strconv.Itoa(e.Column)
+	}
+	return s + ": " + e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// lineReader reads lines of any length, unlike bufio.Scanner which fails on
+// lines longer than 64 KiB. Input beyond the byte limit is an error.
+type lineReader struct {
+	r    *bufio.Reader
+	line []byte
+	num  int
+	err  error
+}
+
+func newLineReader(r io.Reader) *lineReader {
+	return &lineReader{r: bufio.NewReader(limitReader(r))}
+}
+
+// Scan reads the next line, without its line ending.
+func (l *lineReader) Scan() bool {
+	l.line = l.line[:0]
+	for {
+		chunk, isPrefix, err := l.r.ReadLine()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				l.err = err
+			}
+			return false
+		}
+		l.line = append(l.line, chunk...)
+		if !isPrefix {
+			l.num++
+			return true
+		}
+	}
+}
+
+// Bytes returns the current line. It is overwritten by the next call to Scan.
+func (l *lineReader) Bytes() []byte {
+	return l.line
+}
+
+func (l *lineReader) Text() string {
+	return string(l.line)
+}
+
+// Line returns the 1-based number of the current line.
+func (l *lineReader) Line() int {
+	return l.num
+}
+
+func (l *lineReader) Err() error {
+	return l.err
+}
+
+// errorf returns a ParseError for the current line.
+func (l *lineReader) errorf(format string, col int, err error) error {
+	return &ParseError{Format: format, Line: l.num, Column: col, Err: err}
+}
+
+// field is a whitespace-separated field of a line.
+type field struct {
+	text string
+	// col is the 1-based byte offset of the field in the line.
+	col int
+}
+
+// splitFields splits a line around runs of ASCII whitespace like
+// strings.Fields, keeping the column of each field for error messages.
+func splitFields(line []byte) []field {
+	var fields []field
+	start := -1
+	for i, b := range line {
+		switch b {
+		case ' ', '\t', '\v', '\f', '\r', '\n':
+			if start != -1 {
+				fields = append(fields, field{text: string(line[start:i]), col: start + 1})
+				start = -1
+			}
+		default:
+			if start == -1 {
+				start = i
+			}
+		}
+	}
+	if start != -1 {
+		fields = append(fields, field{text: string(line[start:]), col: start + 1})
+	}
+	return fields
+}
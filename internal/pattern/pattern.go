@@ -1,7 +1,9 @@
 package pattern
 
 import (
+	"archive/zip"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"errors"
 	"fmt"
@@ -28,8 +30,8 @@ type Pattern struct {
 	Rule    rule.Rule
 }
 
-func (p Pattern) Step(steps uint64) {
-	p.Tree.Step(&p.Rule, steps)
+func (p Pattern) Step(ctx context.Context, steps uint64, progress quadtree.ProgressFunc) error {
+	return p.Tree.Step(ctx, &p.Rule, steps, progress)
 }
 
 var _ slog.LogValuer = Pattern{}
@@ -68,18 +70,78 @@ var (
 	ErrInvalidHeader       = errors.New("invalid header")
 	ErrUnexpectedCharacter = errors.New("unexpected character")
 	ErrDetectFailed        = errors.New("unable to detect pattern file format")
+	ErrUnknownFormat       = errors.New("unknown pattern file format")
+	ErrMultiState          = errors.New("multi-state patterns are not supported")
+	ErrHeaderMismatch      = errors.New("content does not match header size")
 )
 
 const (
-	ExtRLE       = ".rle"
-	ExtPlaintext = ".cells"
+	ExtRLE           = ".rle"
+	ExtPlaintext     = ".cells"
+	ExtMacrocell     = ".mc"
+	ExtMacrocellGzip = ".mc.gz"
+	// ExtLife and ExtLifeAlt may contain either Life 1.05 or Life 1.06. Files
+	// are read according to their header, but are written as Life 1.06 and
+	// Life 1.05 respectively.
+	ExtLife    = ".lif"
+	ExtLifeAlt = ".life"
 )
 
 func Extensions() []string {
-	return []string{ExtRLE, ExtPlaintext}
+	return []string{ExtRLE, ExtPlaintext, ExtMacrocell, ExtMacrocellGzip, ExtLife, ExtLifeAlt}
 }
 
+// FileExt returns the lowercased extension of a file name, including compound
+// extensions like ".mc.gz".
+func FileExt(name string) string {
+	name = strings.ToLower(name)
+	if strings.HasSuffix(name, ExtMacrocellGzip) {
+		return ExtMacrocellGzip
+	}
+	return path.Ext(name)
+}
+
+// unmarshalNamed reads a pattern, choosing the format from the extension of
+// name. Compressed files are decompressed first.
+func unmarshalNamed(name string, r io.Reader) (*Pattern, error) {
+	switch FileExt(name) {
+	case ExtRLE:
+		return UnmarshalRLE(r)
+	case ExtPlaintext:
+		return UnmarshalPlaintext(r)
+	case ExtMacrocell:
+		return UnmarshalMacrocell(r)
+	case ExtMacrocellGzip, ExtGzip, ExtBzip2:
+		dr, base, err := decompress(name, r)
+		if err != nil {
+			return nil, err
+		}
+		return unmarshalNamed(base, limitReader(dr))
+	case ExtLife, ExtLifeAlt:
+		return UnmarshalLife(r)
+	default:
+		pattern, err := Unmarshal(r)
+		if err != nil {
+			err = fmt.Errorf("%w: %s", err, name)
+		}
+		return pattern, err
+	}
+}
+
+// UnmarshalFile reads a pattern file. Entries inside a zip archive can be
+// loaded with a path like "all.zip!/glider.rle".
 func UnmarshalFile(path string) (*Pattern, error) {
+	if archive, entry, _ := cutArchivePath(path); FileExt(archive) == ExtZip {
+		z, err := zip.OpenReader(archive)
+		if err != nil {
+			return nil, fmt.Errorf("zip: %w", err)
+		}
+		defer func() {
+			_ = z.Close()
+		}()
+		return unmarshalZip(archive, &z.Reader, entry)
+	}
+
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -88,25 +150,78 @@ func UnmarshalFile(path string) (*Pattern, error) {
 		_ = f.Close()
 	}()
 
-	ext := filepath.Ext(path)
-	switch ext {
+	return unmarshalNamed(filepath.Base(path), f)
+}
+
+// MarshalFile writes the pattern to a file, choosing the format from its extension.
+func MarshalFile(path string, p *Pattern) error {
+	var marshal func(io.Writer, *Pattern) error
+	switch ext := FileExt(filepath.Base(path)); ext {
 	case ExtRLE:
-		return UnmarshalRLE(f)
+		marshal = MarshalRLE
 	case ExtPlaintext:
-		return UnmarshalPlaintext(f)
-	default:
-		pattern, err := Unmarshal(f)
-		if err != nil {
-			err = fmt.Errorf("%w: %s", err, path)
+		marshal = MarshalPlaintext
+	case ExtMacrocell:
+		marshal = MarshalMacrocell
+	case ExtMacrocellGzip:
+		marshal = func(w io.Writer, p *Pattern) error {
+			gz := gzip.NewWriter(w)
+			if err := MarshalMacrocell(gz, p); err != nil {
+				return err
+			}
+			return gz.Close()
 		}
-		return pattern, err
+	case ExtLife:
+		marshal = MarshalLife
+	case ExtLifeAlt:
+		marshal = MarshalLife105
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, ext)
 	}
+
+	return writeFileAtomic(path, func(w io.Writer) error {
+		return marshal(w, p)
+	})
+}
+
+// writeFileAtomic writes to a temporary file in the same directory, then
+// renames it over path so a failed write never leaves a truncated file.
+func writeFileAtomic(path string, write func(io.Writer) error) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer func() {
+		_ = os.Remove(tmp)
+	}()
+
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Chmod(mode); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
 }
 
 var ErrResponse = errors.New("HTTP error")
 
+// UnmarshalURL downloads a pattern. Entries inside a zip archive can be
+// loaded with a URL like "https://example.com/all.zip!/glider.rle".
 func UnmarshalURL(ctx context.Context, url string) (*Pattern, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	archive, entry, _ := cutArchivePath(url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, archive, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -124,7 +239,6 @@ func UnmarshalURL(ctx context.Context, url string) (*Pattern, error) {
 		return nil, fmt.Errorf("%w: %s", ErrResponse, resp.Status)
 	}
 
-	ext := path.Ext(url)
 	switch {
 	case strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html"):
 		urls, err := FindHrefPatterns(resp)
@@ -137,27 +251,37 @@ func UnmarshalURL(ctx context.Context, url string) (*Pattern, error) {
 		}
 
 		return UnmarshalURL(ctx, urls[0])
-	case ext == ExtRLE:
-		return UnmarshalRLE(resp.Body)
-	case ext == ExtPlaintext:
-		return UnmarshalPlaintext(resp.Body)
-	default:
-		pattern, err := Unmarshal(resp.Body)
+	case FileExt(archive) == ExtZip:
+		buf, err := io.ReadAll(limitReader(resp.Body))
 		if err != nil {
-			err = fmt.Errorf("%w: %s", err, url)
+			return nil, err
 		}
-		return pattern, err
+		z, err := zip.NewReader(bytes.NewReader(buf), int64(len(buf)))
+		if err != nil {
+			return nil, fmt.Errorf("zip: %w", err)
+		}
+		return unmarshalZip(archive, z, entry)
+	default:
+		return unmarshalNamed(url, resp.Body)
 	}
 }
 
 func Unmarshal(r io.Reader) (*Pattern, error) {
-	buf, err := io.ReadAll(r)
+	buf, err := io.ReadAll(limitReader(r))
 	if err != nil {
 		return nil, err
 	}
 
+	if buf, err = decompressDetected(buf); err != nil {
+		return nil, err
+	}
+
 	firstLine, _, _ := bytes.Cut(bytes.TrimSpace(buf), []byte("\n"))
 	switch {
+	case bytes.HasPrefix(firstLine, []byte(life105Header)), bytes.HasPrefix(firstLine, []byte(life106Header)):
+		return UnmarshalLife(bytes.NewReader(buf))
+	case bytes.HasPrefix(firstLine, []byte(macrocellHeader)):
+		return UnmarshalMacrocell(bytes.NewReader(buf))
 	case bytes.HasPrefix(firstLine, []byte("#")), RLEHeaderRegexp().Match(firstLine):
 		return UnmarshalRLE(bytes.NewReader(buf))
 	case bytes.HasPrefix(firstLine, []byte("!")),
@@ -169,12 +293,33 @@ func Unmarshal(r io.Reader) (*Pattern, error) {
 	}
 }
 
+// ApplyConfig switches the pattern to the stochastic engine if it was requested.
+func (p *Pattern) ApplyConfig(conf *config.Config) {
+	if opts := conf.Stochastic(); opts.Enabled() {
+		s := quadtree.NewStochastic(opts)
+		p.Tree.SetStochastic(s)
+		slog.Info("Using stochastic engine", "seed", s.Seed())
+	}
+}
+
 func New(conf *config.Config) (*Pattern, error) {
+	SetLimits(Limits{
+		Bytes: conf.MaxPatternBytes,
+		Size:  conf.MaxPatternSize,
+		Cells: conf.MaxPatternCells,
+
+		LenientHeaders: conf.LenientHeaders,
+	})
+
 	var r rule.Rule
 	if err := r.UnmarshalText([]byte(conf.RuleString)); err != nil {
 		return nil, err
 	}
 
+	if err := conf.Stochastic().Validate(); err != nil {
+		return nil, err
+	}
+
 	var p *Pattern
 	switch {
 	case conf.Pattern != "":
@@ -194,6 +339,12 @@ func New(conf *config.Config) (*Pattern, error) {
 			if err != nil {
 				return nil, err
 			}
+		case "apg":
+			slog.Info("Loading apgcode", "code", u.Opaque)
+			p, err = UnmarshalAPGCode(u.Opaque)
+			if err != nil {
+				return nil, err
+			}
 		case "http", "https":
 			slog.Info("Loading pattern URL", "url", conf.Pattern)
 			p, err = UnmarshalURL(context.Background(), conf.Pattern)
@@ -216,5 +367,6 @@ func New(conf *config.Config) (*Pattern, error) {
 		p = Default()
 	}
 
+	p.ApplyConfig(conf)
 	return p, nil
 }
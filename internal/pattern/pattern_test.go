@@ -0,0 +1,167 @@
+package pattern
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gabe565.com/cli-of-life/internal/pattern/embedded"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalFile(t *testing.T) {
+	glider, err := UnmarshalRLE(bytes.NewReader(gliderRLE))
+	require.NoError(t, err)
+	require.NoError(t, glider.Step(t.Context(), 1, nil))
+
+	for _, ext := range Extensions() {
+		t.Run(ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "glider"+ext)
+			require.NoError(t, MarshalFile(path, glider))
+
+			got, err := UnmarshalFile(path)
+			require.NoError(t, err)
+			assert.Equal(t, glider.Name, got.Name)
+			assert.Equal(t, glider.Author, got.Author)
+			assert.Equal(t, glider.Comment, got.Comment)
+			assert.Equal(t, glider.Tree.ToSlice(), got.Tree.ToSlice())
+		})
+	}
+
+	t.Run("uppercase extension", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "glider.RLE")
+		require.NoError(t, MarshalFile(path, glider))
+
+		got, err := UnmarshalFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, glider.Tree.ToSlice(), got.Tree.ToSlice())
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		err := MarshalFile(filepath.Join(t.TempDir(), "glider.txt"), glider)
+		require.ErrorIs(t, err, ErrUnknownFormat)
+	})
+}
+
+// testMarshalWhileStepping writes a glider while another goroutine steps it,
+// and checks that every file reads back as a whole glider.
+func testMarshalWhileStepping(
+	t *testing.T, marshal func(io.Writer, *Pattern) error, unmarshal func(io.Reader) (*Pattern, error),
+) {
+	glider, err := UnmarshalRLE(bytes.NewReader(gliderRLE))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan error, 1)
+	go func() {
+		for {
+			if err := glider.Step(ctx, 1, nil); err != nil {
+				if ctx.Err() != nil {
+					err = nil
+				}
+				done <- err
+				return
+			}
+		}
+	}()
+	defer func() {
+		cancel()
+		require.NoError(t, <-done)
+	}()
+
+	for range 2000 {
+		var buf bytes.Buffer
+		require.NoError(t, marshal(&buf, glider))
+		got, err := unmarshal(&buf)
+		require.NoError(t, err, buf.String())
+		require.Equal(t, 5, got.Tree.Stats().Population, buf.String())
+	}
+}
+
+func TestFileExt(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"glider.rle", ExtRLE},
+		{"glider.RLE", ExtRLE},
+		{"glider.mc.gz", ExtMacrocellGzip},
+		{"glider.MC.GZ", ExtMacrocellGzip},
+		{"glider", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FileExt(tt.name))
+		})
+	}
+}
+
+func Test_writeFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "glider.rle")
+	require.NoError(t, os.WriteFile(path, []byte("original"), 0o600))
+
+	errWrite := errors.New("write failed")
+	err := writeFileAtomic(path, func(w io.Writer) error {
+		_, _ = w.Write([]byte("partial"))
+		return errWrite
+	})
+	require.ErrorIs(t, err, errWrite)
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "original", string(b))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary file should be removed")
+
+	require.NoError(t, writeFileAtomic(path, func(w io.Writer) error {
+		_, err := w.Write([]byte("updated"))
+		return err
+	}))
+	b, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "updated", string(b))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+}
+
+func FuzzUnmarshal(f *testing.F) {
+	require.NoError(f, fs.WalkDir(embedded.Embedded, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := fs.ReadFile(embedded.Embedded, path)
+		f.Add(b)
+		return err
+	}))
+	f.Add(gliderPlaintext)
+	f.Add([]byte("#CXRLE Pos=-1,-2 Gen=5\nx = 3, y = 2\nA.A$.2A!"))
+	f.Add([]byte("#P 0 0\nx = 1, y = 1\no!\n#P 10 -10\nx = 2, y = 1\nbo!\n"))
+	f.Add([]byte("[M2] (golly 4.3)\n#R B3/S23\n.*$..*$***$\n4 1 0 0 0\n5 0 2 2 0\n"))
+	f.Add([]byte("#Life 1.05\n#D Name: Glider\n#N\n#P -1 -1\n.*\n..*\n***\n"))
+	f.Add([]byte("#Life 1.06\n0 -1\n1 0\n-1 1\n0 1\n1 1\n"))
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		setLimits(t, Limits{Bytes: 1 << 16, Size: 1 << 12, Cells: 1 << 12})
+		p, err := Unmarshal(bytes.NewReader(b))
+		if err != nil {
+			return
+		}
+		limits := currentLimits()
+		assert.LessOrEqual(t, p.Tree.Stats().Population, limits.Cells)
+		size := p.Tree.FilledCoords().Size()
+		assert.LessOrEqual(t, size.X, limits.Size)
+		assert.LessOrEqual(t, size.Y, limits.Size)
+	})
+}
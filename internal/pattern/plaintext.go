@@ -1,17 +1,20 @@
 package pattern
 
 import (
-	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"image"
 	"io"
+	"strings"
 )
 
 func UnmarshalPlaintext(r io.Reader) (*Pattern, error) {
 	pattern := Default()
-	scanner := bufio.NewScanner(r)
+	scanner := newLineReader(r)
+	builder := newBuilder()
 	var p image.Point
+	var width int
 	for scanner.Scan() {
 		line := scanner.Bytes()
 		switch {
@@ -27,26 +30,93 @@ func UnmarshalPlaintext(r io.Reader) (*Pattern, error) {
 				pattern.Comment += string(comment)
 			}
 		default:
-			pattern.Tree.GrowToFit(p.Add(image.Pt(0, len(line))))
-			for _, b := range line {
+			width = max(width, len(line))
+			for i, b := range line {
 				switch b {
 				case '.':
-					pattern.Tree.Set(p, 0)
 					p.X++
 				case 'O', '*':
-					pattern.Tree.Set(p, 1)
+					if err := builder.Set(p); err != nil {
+						return nil, scanner.errorf("plaintext", i+1, err)
+					}
 					p.X++
 				default:
-					return nil, fmt.Errorf("plaintext: %w: %q in line: %q", ErrUnexpectedCharacter, string(b), line)
+					return nil, scanner.errorf("plaintext", i+1, fmt.Errorf("%w: %q", ErrUnexpectedCharacter, string(b)))
 				}
 			}
 			p.X = 0
 			p.Y++
 		}
 	}
-	if scanner.Err() != nil {
-		return nil, fmt.Errorf("plaintext: %w", scanner.Err())
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("plaintext: %w", err)
+	}
+	pattern.Tree.SetCells(builder.Node())
+	if err := pattern.Tree.GrowToFit(image.Pt(width, p.Y)); err != nil {
+		return nil, fmt.Errorf("plaintext: %w", err)
 	}
 	pattern.Tree.SetReset()
 	return pattern, nil
 }
+
+// plaintextMaxSize is the largest width or height written in plaintext format.
+// Every cell takes a byte, so larger patterns should be saved as RLE instead.
+const plaintextMaxSize = 1024
+
+var ErrPatternTooLarge = errors.New("pattern is too large")
+
+// MarshalPlaintext writes the pattern's current generation in plaintext format.
+func MarshalPlaintext(w io.Writer, p *Pattern) error {
+	// Read the bounds and cells from one snapshot, so the rows line up even if
+	// the pattern is stepped concurrently.
+	root := p.Tree.Snapshot().Root()
+	bounds := root.FilledCoords()
+	size := bounds.Size()
+	if size.X > plaintextMaxSize || size.Y > plaintextMaxSize {
+		return fmt.Errorf("plaintext: %w: %dx%d exceeds %dx%d, use RLE instead",
+			ErrPatternTooLarge, size.X, size.Y, plaintextMaxSize, plaintextMaxSize)
+	}
+
+	var buf bytes.Buffer
+	if p.Name != "" {
+		buf.WriteString("!Name: " + p.Name + "\n")
+	}
+	if p.Author != "" {
+		buf.WriteString("!Author: " + p.Author + "\n")
+	}
+	if p.Comment != "" {
+		for line := range strings.Lines(p.Comment) {
+			buf.WriteString("!" + strings.TrimRight(line, "\r\n") + "\n")
+		}
+	}
+
+	// Rows are trimmed after the last live cell, but empty rows keep a single
+	// dot so that they are not mistaken for blank lines.
+	row := bytes.Repeat([]byte("."), size.X)
+	var end int
+	writeRow := func() {
+		buf.Write(row[:max(end, 1)])
+		buf.WriteByte('\n')
+		for i := range end {
+			row[i] = '.'
+		}
+		end = 0
+	}
+	y := bounds.Min.Y
+	for run := range root.Runs() {
+		for ; y < run.Y; y++ {
+			writeRow()
+		}
+		start := run.X - bounds.Min.X
+		for i := range run.Len {
+			row[start+i] = 'O'
+		}
+		end = start + run.Len
+	}
+	if size.Y != 0 {
+		writeRow()
+	}
+
+	_, err := buf.WriteTo(w)
+	return err
+}
@@ -3,6 +3,7 @@ package pattern
 import (
 	"bytes"
 	_ "embed"
+	"image"
 	"io"
 	"testing"
 
@@ -52,3 +53,43 @@ func TestUnmarshalPlaintext(t *testing.T) {
 		})
 	}
 }
+
+func TestMarshalPlaintext(t *testing.T) {
+	glider, err := UnmarshalPlaintext(bytes.NewReader(gliderPlaintext))
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, MarshalPlaintext(&buf, glider))
+	assert.Equal(t, string(gliderPlaintext), buf.String())
+
+	t.Run("empty rows", func(t *testing.T) {
+		p := Default()
+		require.NoError(t, p.Tree.Set(image.Pt(-2, -1), 1))
+		require.NoError(t, p.Tree.Set(image.Pt(1, 2), 1))
+
+		var buf bytes.Buffer
+		require.NoError(t, MarshalPlaintext(&buf, p))
+		assert.Equal(t, "O\n.\n.\n...O\n", buf.String())
+
+		got, err := UnmarshalPlaintext(&buf)
+		require.NoError(t, err)
+		assert.Equal(t, p.Tree.ToSlice(), got.Tree.ToSlice())
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, MarshalPlaintext(&buf, Default()))
+		assert.Empty(t, buf.String())
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		p := Default()
+		require.NoError(t, p.Tree.Set(image.Pt(0, 0), 1))
+		require.NoError(t, p.Tree.Set(image.Pt(plaintextMaxSize, 0), 1))
+		require.ErrorIs(t, MarshalPlaintext(io.Discard, p), ErrPatternTooLarge)
+	})
+}
+
+func TestMarshalPlaintext_stepping(t *testing.T) {
+	testMarshalWhileStepping(t, MarshalPlaintext, UnmarshalPlaintext)
+}
@@ -1,14 +1,17 @@
 package pattern
 
 import (
-	"bufio"
 	"bytes"
 	"fmt"
 	"image"
 	"io"
+	"log/slog"
+	"math/big"
 	"regexp"
 	"strconv"
+	"strings"
 
+	"gabe565.com/cli-of-life/internal/quadtree"
 	"gabe565.com/cli-of-life/internal/rule"
 )
 
@@ -16,14 +19,61 @@ func RLEHeaderRegexp() *regexp.Regexp {
 	return regexp.MustCompile(`^x *= *(?P<x>[^,]+), *y *= *(?P<y>[^,]+)(?:, *rule *= *(?P<rule>.+))?$`)
 }
 
+// UnmarshalRLE reads a pattern in RLE format. The pattern is placed at the
+// position given by a "#P", "#R", or Golly "#CXRLE" line, or at the origin if
+// there is none. Xlife "#P" and "#R" lines may also start additional parts
+// after the end of the previous one.
 func UnmarshalRLE(r io.Reader) (*Pattern, error) {
 	pattern := Default()
-	scanner := bufio.NewScanner(r)
-	var p image.Point
-scan:
+	scanner := newLineReader(r)
+	builder := newBuilder()
+	var origin, p, size image.Point
+	var header bool
+	var content image.Rectangle
+	var gen *big.Int
+	var done bool
+	// endPart checks that the cells of a part fit in its header. Unless lenient
+	// headers are enabled, a mismatch is an error.
+	endPart := func() error {
+		defer func() {
+			header, content = false, image.Rectangle{}
+		}()
+		if !header || content.In(image.Rectangle{Max: size}) {
+			return nil
+		}
+		err := fmt.Errorf("%w: header is %s, content is %s", ErrHeaderMismatch, size, content.Size())
+		if !builder.limits.LenientHeaders {
+			return err
+		}
+		slog.Warn("RLE content does not match header size", "line", scanner.Line(), "error", err)
+		return nil
+	}
 	for scanner.Scan() {
 		line := scanner.Bytes()
 		switch {
+		case bytes.HasPrefix(line, []byte("#P")), bytes.HasPrefix(line, []byte("#R")):
+			// Lines which are not coordinates are ignored like other unknown lines.
+			if pos, col, err := parseLifePoint(line, 2); err == nil {
+				if origin, err = builder.local(pos); err != nil {
+					return nil, scanner.errorf("rle", col, err)
+				}
+				if !done {
+					if err := endPart(); err != nil {
+						return nil, scanner.errorf("rle", 0, err)
+					}
+				}
+				p, done = image.Point{}, false
+			}
+		case bytes.HasPrefix(line, []byte("#CXRLE")):
+			pos, xgen, err := parseXRLE(line)
+			if err != nil {
+				return nil, scanner.errorf("rle", 0, err)
+			}
+			if origin, err = builder.local(pos); err != nil {
+				return nil, scanner.errorf("rle", 0, err)
+			}
+			gen = xgen
+		case done:
 		case bytes.HasPrefix(line, []byte("#")):
 			if name, found := bytes.CutPrefix(line, []byte("#N ")); found {
 				pattern.Name = string(bytes.TrimSpace(name))
@@ -42,7 +92,7 @@ scan:
 			matches := headerRe.FindStringSubmatch(scanner.Text())
 
 			if len(matches) == 0 {
-				return nil, fmt.Errorf("rle: %w: %q", ErrInvalidHeader, line)
+				return nil, scanner.errorf("rle", 0, fmt.Errorf("%w: %q", ErrInvalidHeader, line))
 			}
 
 			var w, h int
@@ -50,12 +100,12 @@ scan:
 			for i, name := range headerRe.SubexpNames() {
 				switch name {
 				case "x":
-					if w, err = strconv.Atoi(matches[i]); err != nil {
-						return nil, fmt.Errorf("rle: parsing header x: %w", err)
+					if w, err = strconv.Atoi(matches[i]); err != nil || w < 0 {
+						return nil, scanner.errorf("rle", 0, fmt.Errorf("%w: x = %q", ErrInvalidHeader, matches[i]))
 					}
 				case "y":
-					if h, err = strconv.Atoi(matches[i]); err != nil {
-						return nil, fmt.Errorf("rle: parsing header y: %w", err)
+					if h, err = strconv.Atoi(matches[i]); err != nil || h < 0 {
+						return nil, scanner.errorf("rle", 0, fmt.Errorf("%w: y = %q", ErrInvalidHeader, matches[i]))
 					}
 				case "rule":
 					switch matches[i] {
@@ -63,24 +113,33 @@ scan:
 						pattern.Rule = rule.GameOfLife()
 					default:
 						if err := pattern.Rule.UnmarshalText([]byte(matches[i])); err != nil {
-							return nil, fmt.Errorf("rle: %w", err)
+							return nil, scanner.errorf("rle", 0, err)
 						}
 					}
 				}
 			}
 
-			pattern.Tree.GrowToFit(image.Pt(w, h))
+			size = image.Pt(w, h)
+			if err := builder.limits.checkSize(size); err != nil {
+				return nil, scanner.errorf("rle", 0, err)
+			}
+			header = true
 		default:
 			if len(line) == 0 {
 				continue
 			}
 
 			var runCount int
-			for _, b := range line {
-				switch {
+			for i := 0; i < len(line) && !done; i++ {
+				col := i + 1
+				switch b := line[i]; {
 				case b >= '0' && b <= '9':
-					runCount *= 10
-					runCount += int(b - '0')
+					// Runs are limited to half the universe, so moving past one can't overflow.
+					const maxRun = 1 << (quadtree.MaxLevel - 1)
+					if runCount > (maxRun-int(b-'0'))/10 {
+						return nil, scanner.errorf("rle", col, fmt.Errorf("%w: run count", quadtree.ErrUniverseOverflow))
+					}
+					runCount = runCount*10 + int(b-'0')
 				case b == '$':
 					runCount = max(runCount, 1)
 					if p.X != 0 || p.Y != 0 {
@@ -89,30 +148,152 @@ scan:
 					}
 					runCount = 0
 				case b == '!':
-					break scan
-				default:
+					done = true
+					if err := endPart(); err != nil {
+						return nil, scanner.errorf("rle", col, err)
+					}
+				case b == ' ':
+				case b == 'b' || b == '.' || b == 'o':
 					runCount = max(runCount, 1)
-					switch b {
-					case 'b':
-						for range runCount {
-							pattern.Tree.Set(p, 0)
-							p.X++
-						}
-					case ' ':
-					default:
-						for range runCount {
-							pattern.Tree.Set(p, 1)
-							p.X++
+					if b == 'o' {
+						if err := builder.SetRun(origin.Add(p), runCount); err != nil {
+							return nil, scanner.errorf("rle", col, err)
 						}
+						content = content.Union(image.Rect(p.X, p.Y, p.X+runCount, p.Y+1))
 					}
+					p.X += runCount
 					runCount = 0
+				case b >= 'A' && b <= 'X', b >= 'p' && b <= 'y':
+					// Multi-state tags, which the two-state quadtree can't store.
+					return nil, scanner.errorf("rle", col, fmt.Errorf("%w: %q", ErrMultiState, string(b)))
+				default:
+					return nil, scanner.errorf("rle", col, fmt.Errorf("%w: %q", ErrUnexpectedCharacter, string(b)))
+				}
+				if !quadtree.InBounds(origin.Add(p)) {
+					return nil, scanner.errorf("rle", col, fmt.Errorf("%w: %s", quadtree.ErrUniverseOverflow, origin.Add(p)))
 				}
 			}
 		}
 	}
-	if scanner.Err() != nil {
-		return nil, fmt.Errorf("rle: %w", scanner.Err())
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("rle: %w", err)
+	}
+	if !done {
+		if err := endPart(); err != nil {
+			return nil, scanner.errorf("rle", 0, err)
+		}
+	}
+	builder.apply(pattern.Tree)
+	if err := pattern.Tree.GrowToFit(origin.Add(size)); err != nil {
+		return nil, fmt.Errorf("rle: %w", err)
+	}
+	if gen != nil {
+		pattern.Tree.SetGeneration(gen)
 	}
 	pattern.Tree.SetReset()
 	return pattern, nil
 }
+
+// parseXRLE parses the position and generation from a Golly header like
+// "#CXRLE Pos=-10,5 Gen=100". The position may be arbitrarily large.
+func parseXRLE(line []byte) (quadtree.BigPoint, *big.Int, error) {
+	var pos quadtree.BigPoint
+	var gen *big.Int
+	for _, field := range strings.Fields(string(line))[1:] {
+		k, v, _ := strings.Cut(field, "=")
+		switch k {
+		case "Pos":
+			x, y, found := strings.Cut(v, ",")
+			if !found {
+				return pos, nil, fmt.Errorf("%w: %q", ErrInvalidHeader, line)
+			}
+			var okX, okY bool
+			pos.X, okX = new(big.Int).SetString(x, 10)
+			pos.Y, okY = new(big.Int).SetString(y, 10)
+			if !okX || !okY {
+				return quadtree.BigPoint{}, nil, fmt.Errorf("%w: %q", ErrInvalidHeader, line)
+			}
+		case "Gen":
+			var ok bool
+			if gen, ok = new(big.Int).SetString(v, 10); !ok || gen.Sign() < 0 {
+				return pos, nil, fmt.Errorf("%w: %q", ErrInvalidHeader, line)
+			}
+		}
+	}
+	return pos, gen, nil
+}
+
+// rleLineWidth is the maximum length of a line of encoded cells.
+const rleLineWidth = 70
+
+// MarshalRLE writes the pattern's current generation in RLE format.
+func MarshalRLE(w io.Writer, p *Pattern) error {
+	// Read everything from one snapshot, so the header matches the cells even
+	// if the pattern is stepped concurrently.
+	snapshot := p.Tree.Snapshot()
+	root := snapshot.Root()
+	bounds := root.FilledCoords()
+	gen := snapshot.Generation()
+	origin := snapshot.Offset().Add(bounds.Min)
+
+	var buf bytes.Buffer
+	if !origin.IsZero() || gen.Sign() != 0 {
+		buf.WriteString("#CXRLE Pos=" + origin.X.String() + "," + origin.Y.String())
+		if gen.Sign() != 0 {
+			buf.WriteString(" Gen=" + gen.String())
+		}
+		buf.WriteByte('\n')
+	}
+	if p.Name != "" {
+		buf.WriteString("#N " + p.Name + "\n")
+	}
+	if p.Author != "" {
+		buf.WriteString("#O " + p.Author + "\n")
+	}
+	if p.Comment != "" {
+		for line := range strings.Lines(p.Comment) {
+			buf.WriteString("#C " + strings.TrimRight(line, "\r\n") + "\n")
+		}
+	}
+
+	size := bounds.Size()
+	buf.WriteString("x = " + strconv.Itoa(size.X) + ", y = " + strconv.Itoa(size.Y) +
+		", rule = " + p.Rule.String() + "\n")
+
+	enc := rleEncoder{buf: &buf}
+	pos := bounds.Min
+	for run := range root.Runs() {
+		if run.Y > pos.Y {
+			enc.write(run.Y-pos.Y, '$')
+			pos = image.Pt(bounds.Min.X, run.Y)
+		}
+		if run.X > pos.X {
+			enc.write(run.X-pos.X, 'b')
+		}
+		enc.write(run.Len, 'o')
+		pos.X = run.X + run.Len
+	}
+	enc.write(1, '!')
+	buf.WriteByte('\n')
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// rleEncoder writes run-length encoded tags, wrapping lines at rleLineWidth.
+type rleEncoder struct {
+	buf  *bytes.Buffer
+	line int
+}
+
+func (e *rleEncoder) write(count int, tag byte) {
+	token := string(tag)
+	if count > 1 {
+		token = strconv.Itoa(count) + token
+	}
+	if e.line != 0 && e.line+len(token) > rleLineWidth {
+		e.buf.WriteByte('\n')
+		e.line = 0
+	}
+	e.buf.WriteString(token)
+	e.line += len(token)
+}
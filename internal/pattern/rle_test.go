@@ -3,10 +3,15 @@ package pattern
 import (
 	"bytes"
 	_ "embed"
+	"image"
 	"io"
+	"math/big"
+	"math/rand/v2"
+	"slices"
 	"strings"
 	"testing"
 
+	"gabe565.com/cli-of-life/internal/quadtree"
 	"gabe565.com/cli-of-life/internal/rule"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -111,16 +116,20 @@ func TestUnmarshalRLE(t *testing.T) {
 		{
 			"edge case incorrect x",
 			args{strings.NewReader("x = 0, y = 1\no!")},
-			&Pattern{Rule: rule.GameOfLife()},
-			[][]int{{1}},
-			require.NoError,
+			nil,
+			nil,
+			func(t require.TestingT, err error, _ ...any) {
+				require.ErrorIs(t, err, ErrHeaderMismatch)
+			},
 		},
 		{
 			"edge case incorrect y",
 			args{strings.NewReader("x = 1, y = 0\no!")},
-			&Pattern{Rule: rule.GameOfLife()},
-			[][]int{{1}},
-			require.NoError,
+			nil,
+			nil,
+			func(t require.TestingT, err error, _ ...any) {
+				require.ErrorIs(t, err, ErrHeaderMismatch)
+			},
 		},
 		{
 			"blank lines",
@@ -128,6 +137,52 @@ func TestUnmarshalRLE(t *testing.T) {
 			&Pattern{Rule: rule.GameOfLife()},
 			[][]int{{1}},
 			require.NoError,
+		}, {
+			"dot is dead",
+			args{strings.NewReader("x = 3, y = 1\no.o!")},
+			&Pattern{Rule: rule.GameOfLife()},
+			[][]int{{1, 0, 1}},
+			require.NoError,
+		},
+		{
+			"multi-state",
+			args{strings.NewReader("x = 3, y = 2\nA.A$.2A!")},
+			nil,
+			nil,
+			func(t require.TestingT, err error, _ ...any) {
+				require.ErrorIs(t, err, ErrMultiState)
+			},
+		},
+		{
+			"multi-state prefixed",
+			args{strings.NewReader("x = 2, y = 1\no2pC!")},
+			nil,
+			nil,
+			func(t require.TestingT, err error, _ ...any) {
+				require.ErrorIs(t, err, ErrMultiState)
+				require.ErrorContains(t, err, `"p"`)
+			},
+		},
+		{
+			"unknown tag",
+			args{strings.NewReader("x = 3, y = 1\noz*!")},
+			nil,
+			nil,
+			func(t require.TestingT, err error, _ ...any) {
+				require.ErrorIs(t, err, ErrUnexpectedCharacter)
+				var parseErr *ParseError
+				require.ErrorAs(t, err, &parseErr)
+				assert.Equal(t, 2, parseErr.Column)
+			},
+		},
+		{
+			"percent",
+			args{strings.NewReader("x = 1, y = 1\n%!")},
+			nil,
+			nil,
+			func(t require.TestingT, err error, _ ...any) {
+				require.ErrorIs(t, err, ErrUnexpectedCharacter)
+			},
 		},
 	}
 	for _, tt := range tests {
@@ -144,3 +199,198 @@ func TestUnmarshalRLE(t *testing.T) {
 		})
 	}
 }
+
+func TestUnmarshalRLE_position(t *testing.T) {
+	tests := []struct {
+		name      string
+		rle       string
+		wantCells []image.Point
+		wantGen   *big.Int
+	}{
+		{
+			"xlife P",
+			"#P -5 3\nx = 2, y = 1\n2o!\n",
+			[]image.Point{{-5, 3}, {-4, 3}},
+			big.NewInt(0),
+		},
+		{
+			"xlife R",
+			"#R 2 -1\nx = 1, y = 2\no$o!\n",
+			[]image.Point{{2, -1}, {2, 0}},
+			big.NewInt(0),
+		},
+		{
+			"cxrle",
+			"#CXRLE Pos=-1,-2 Gen=12345678901234567890\nx = 1, y = 1, rule = B3/S23\no!\n",
+			[]image.Point{{-1, -2}},
+			func() *big.Int {
+				gen, _ := new(big.Int).SetString("12345678901234567890", 10)
+				return gen
+			}(),
+		},
+		{
+			"multiple parts",
+			"#P 0 0\nx = 1, y = 1\no!\n#P 10 -10\nx = 2, y = 1\nbo!\n",
+			[]image.Point{{11, -10}, {0, 0}},
+			big.NewInt(0),
+		},
+		{
+			"trailing text",
+			"x = 1, y = 1\no!\n#Part of a comment\nooo\n",
+			[]image.Point{{0, 0}},
+			big.NewInt(0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := UnmarshalRLE(strings.NewReader(tt.rle))
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCells, slices.Collect(got.Tree.All()))
+			assert.Equal(t, tt.wantGen, got.Tree.Stats().Generation)
+		})
+	}
+
+	t.Run("invalid cxrle", func(t *testing.T) {
+		_, err := UnmarshalRLE(strings.NewReader("#CXRLE Pos=1\nx = 1, y = 1\no!\n"))
+		require.ErrorIs(t, err, ErrInvalidHeader)
+	})
+}
+
+func TestMarshalRLE(t *testing.T) {
+	glider, err := UnmarshalRLE(bytes.NewReader(gliderRLE))
+	require.NoError(t, err)
+
+	wide := Default()
+	for x := range 40 {
+		require.NoError(t, wide.Tree.Set(image.Pt(x*3, 0), 1))
+	}
+	require.NoError(t, wide.Tree.Set(image.Pt(0, 5), 1))
+
+	tests := []struct {
+		name string
+		p    *Pattern
+		want string
+	}{
+		{"empty", Default(), "x = 0, y = 0, rule = B3/S23\n!\n"},
+		{
+			"glider",
+			glider,
+			"#N Glider\n" +
+				"#O Richard K. Guy\n" +
+				"#C The smallest, most common, and first discovered spaceship. Diagonal, has period 4 and speed c/4.\n" +
+				"#C www.conwaylife.com/wiki/index.php?title=Glider\n" +
+				"x = 3, y = 3, rule = B3/S23\n" +
+				"bo$2bo$3o!\n",
+		},
+		{
+			"wrapped",
+			wide,
+			"x = 118, y = 6, rule = B3/S23\n" +
+				strings.Repeat("o2b", 23) + "o\n" +
+				strings.Repeat("2bo", 16) + "5$o!\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, MarshalRLE(&buf, tt.p))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestMarshalRLE_roundTrip(t *testing.T) {
+	p := Default()
+	p.Name = "Random"
+	p.Author = "Test"
+	p.Comment = "First line\nSecond line"
+	p.Rule = rule.HighLife()
+	r := rand.New(rand.NewPCG(1, 2)) //nolint:gosec
+	for range 2000 {
+		require.NoError(t, p.Tree.Set(image.Pt(r.IntN(200)-100, r.IntN(150)-50), 1))
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, MarshalRLE(&buf, p))
+	for line := range strings.Lines(buf.String()) {
+		if !strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "x") {
+			assert.LessOrEqual(t, len(strings.TrimSuffix(line, "\n")), rleLineWidth)
+		}
+	}
+
+	got, err := UnmarshalRLE(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, p.Name, got.Name)
+	assert.Equal(t, p.Author, got.Author)
+	assert.Equal(t, p.Comment, got.Comment)
+	assert.Equal(t, p.Rule, got.Rule)
+	assert.Equal(t, p.Tree.ToSlice(), got.Tree.ToSlice())
+	assert.Equal(t, p.Tree.FilledCoords().Size(), got.Tree.FilledCoords().Size())
+}
+
+func TestMarshalRLE_position(t *testing.T) {
+	glider, err := UnmarshalRLE(bytes.NewReader(gliderRLE))
+	require.NoError(t, err)
+	require.NoError(t, glider.Step(t.Context(), 5, nil))
+
+	var buf bytes.Buffer
+	require.NoError(t, MarshalRLE(&buf, glider))
+	assert.True(t, strings.HasPrefix(buf.String(), "#CXRLE Pos=1,2 Gen=5\n"), buf.String())
+
+	got, err := UnmarshalRLE(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, slices.Collect(glider.Tree.All()), slices.Collect(got.Tree.All()))
+	assert.Equal(t, glider.Tree.Stats().Generation, got.Tree.Stats().Generation)
+}
+
+func TestMarshalRLE_bigPosition(t *testing.T) {
+	const rle = "#CXRLE Pos=1000000000000000000000000000000,-5\nx = 3, y = 3, rule = B3/S23\nbo$2bo$3o!\n"
+	glider, err := UnmarshalRLE(strings.NewReader(rle))
+	require.NoError(t, err)
+	x, _ := new(big.Int).SetString("1000000000000000000000000000000", 10)
+	assert.Equal(t, quadtree.BigPoint{X: x, Y: big.NewInt(-5)}, glider.Tree.Offset())
+	assert.Equal(t, []image.Point{{1, 0}, {2, 1}, {0, 2}, {1, 2}, {2, 2}}, slices.Collect(glider.Tree.All()))
+
+	var buf bytes.Buffer
+	require.NoError(t, MarshalRLE(&buf, glider))
+	assert.Equal(t, rle, buf.String())
+
+	require.NoError(t, glider.Step(t.Context(), 4, nil))
+	buf.Reset()
+	require.NoError(t, MarshalRLE(&buf, glider))
+	assert.True(t, strings.HasPrefix(buf.String(), "#CXRLE Pos=1000000000000000000000000000001,-4 Gen=4\n"), buf.String())
+
+	t.Run("parts too far apart", func(t *testing.T) {
+		_, err := UnmarshalRLE(strings.NewReader(rle + "#P 0 0\nx = 1, y = 1\no!\n"))
+		require.ErrorIs(t, err, quadtree.ErrUniverseOverflow)
+	})
+}
+
+func TestMarshalRLE_stepping(t *testing.T) {
+	testMarshalWhileStepping(t, MarshalRLE, UnmarshalRLE)
+}
+
+func FuzzUnmarshalRLE(f *testing.F) {
+	f.Add(gliderRLE)
+	f.Add([]byte("x = 3, y = 3, rule = B36/S23\n3o$obo$3o!"))
+	f.Add([]byte("#CXRLE Pos=-1,-2 Gen=5\nx = 3, y = 2\nA.A$.2A!"))
+	f.Add([]byte("#P 0 0\nx = 1, y = 1\no!\n#R 10 -10\nx = 2, y = 1\nbo$$3o!\n"))
+	f.Add([]byte("x = 1, y = 1\n$o$o2pA!"))
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		setLimits(t, Limits{Bytes: 1 << 16, Size: 1 << 12, Cells: 1 << 12})
+		p, err := UnmarshalRLE(bytes.NewReader(b))
+		if err != nil {
+			return
+		}
+
+		// Anything which loads should be written and read back unchanged.
+		var buf bytes.Buffer
+		require.NoError(t, MarshalRLE(&buf, p))
+		got, err := UnmarshalRLE(&buf)
+		require.NoError(t, err, buf.String())
+		assert.Equal(t, slices.Collect(p.Tree.All()), slices.Collect(got.Tree.All()))
+		assert.Equal(t, p.Tree.Stats().Generation, got.Tree.Stats().Generation)
+		assert.Equal(t, p.Rule.String(), got.Rule.String())
+	})
+}
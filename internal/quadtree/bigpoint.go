@@ -0,0 +1,65 @@
+package quadtree
+
+import (
+	"image"
+	"math/big"
+)
+
+// BigPoint is a point with arbitrary-precision coordinates. Nil coordinates
+// are treated as zero, and its methods never modify the receiver.
+type BigPoint struct {
+	X, Y *big.Int
+}
+
+// BigPt returns a BigPoint with the given coordinates.
+func BigPt(x, y int64) BigPoint {
+	return BigPoint{X: big.NewInt(x), Y: big.NewInt(y)}
+}
+
+func bigOrZero(i *big.Int) *big.Int {
+	if i == nil {
+		return new(big.Int)
+	}
+	return i
+}
+
+// Add returns p+q.
+func (p BigPoint) Add(q image.Point) BigPoint {
+	return BigPoint{
+		X: new(big.Int).Add(bigOrZero(p.X), big.NewInt(int64(q.X))),
+		Y: new(big.Int).Add(bigOrZero(p.Y), big.NewInt(int64(q.Y))),
+	}
+}
+
+// Sub returns p-q.
+func (p BigPoint) Sub(q BigPoint) BigPoint {
+	return BigPoint{
+		X: new(big.Int).Sub(bigOrZero(p.X), bigOrZero(q.X)),
+		Y: new(big.Int).Sub(bigOrZero(p.Y), bigOrZero(q.Y)),
+	}
+}
+
+// Point returns p as an image.Point. If either coordinate doesn't fit in an
+// int, ok is false.
+func (p BigPoint) Point() (image.Point, bool) {
+	x, y := bigOrZero(p.X), bigOrZero(p.Y)
+	if !x.IsInt64() || !y.IsInt64() {
+		return image.Point{}, false
+	}
+	return image.Pt(int(x.Int64()), int(y.Int64())), true
+}
+
+// IsZero reports whether both coordinates are zero.
+func (p BigPoint) IsZero() bool {
+	return bigOrZero(p.X).Sign() == 0 && bigOrZero(p.Y).Sign() == 0
+}
+
+// Eq reports whether p and q are equal.
+func (p BigPoint) Eq(q BigPoint) bool {
+	return bigOrZero(p.X).Cmp(bigOrZero(q.X)) == 0 && bigOrZero(p.Y).Cmp(bigOrZero(q.Y)) == 0
+}
+
+// String returns a string representation of p like "(3,4)".
+func (p BigPoint) String() string {
+	return "(" + bigOrZero(p.X).String() + "," + bigOrZero(p.Y).String() + ")"
+}
@@ -0,0 +1,91 @@
+package quadtree
+
+import (
+	"image"
+	"math"
+)
+
+// FilledCoords returns the bounding box of all live cells. Each edge is found
+// by descending only into the outermost non-empty children, so the cost is
+// proportional to the tree's perimeter rather than its population.
+func (n *Node) FilledCoords() image.Rectangle {
+	if n.value == 0 {
+		return image.Rectangle{}
+	}
+	w := n.halfWidth()
+	return image.Rect(n.minX()-w, n.minY()-w, n.maxX()-w+1, n.maxY()-w+1)
+}
+
+// outermost returns the children on one side of a node if any of them are
+// non-empty, otherwise the children on the opposite side. The offset of the
+// returned children along the searched axis is returned with them.
+func outermost(side, opposite [2]*Node, sideOffset, oppositeOffset int) ([2]*Node, int) {
+	if side[0].value == 0 && side[1].value == 0 {
+		return opposite, oppositeOffset
+	}
+	return side, sideOffset
+}
+
+// minX returns the smallest x of a live cell relative to the node's left edge.
+// The node must not be empty.
+func (n *Node) minX() int {
+	if n.level == LeafLevel {
+		return leafMinX(n.bits)
+	}
+	children, offset := outermost([2]*Node{n.NW, n.SW}, [2]*Node{n.NE, n.SE}, 0, n.halfWidth())
+	x := math.MaxInt
+	for _, child := range children {
+		if child.value != 0 {
+			x = min(x, child.minX())
+		}
+	}
+	return offset + x
+}
+
+// minY returns the smallest y of a live cell relative to the node's top edge.
+// The node must not be empty.
+func (n *Node) minY() int {
+	if n.level == LeafLevel {
+		return leafMinY(n.bits)
+	}
+	children, offset := outermost([2]*Node{n.NW, n.NE}, [2]*Node{n.SW, n.SE}, 0, n.halfWidth())
+	y := math.MaxInt
+	for _, child := range children {
+		if child.value != 0 {
+			y = min(y, child.minY())
+		}
+	}
+	return offset + y
+}
+
+// maxX returns the largest x of a live cell relative to the node's left edge.
+// The node must not be empty.
+func (n *Node) maxX() int {
+	if n.level == LeafLevel {
+		return leafMaxX(n.bits)
+	}
+	children, offset := outermost([2]*Node{n.NE, n.SE}, [2]*Node{n.NW, n.SW}, n.halfWidth(), 0)
+	x := math.MinInt
+	for _, child := range children {
+		if child.value != 0 {
+			x = max(x, child.maxX())
+		}
+	}
+	return offset + x
+}
+
+// maxY returns the largest y of a live cell relative to the node's top edge.
+// The node must not be empty.
+func (n *Node) maxY() int {
+	if n.level == LeafLevel {
+		return leafMaxY(n.bits)
+	}
+	children, offset := outermost([2]*Node{n.SW, n.SE}, [2]*Node{n.NW, n.NE}, n.halfWidth(), 0)
+	y := math.MinInt
+	for _, child := range children {
+		if child.value != 0 {
+			y = max(y, child.maxY())
+		}
+	}
+	return offset + y
+}
@@ -0,0 +1,127 @@
+package quadtree
+
+import (
+	"bytes"
+	"image"
+	"strings"
+)
+
+const (
+	brailleWidth  = 2
+	brailleHeight = 4
+	brailleBlank  = '⠀'
+)
+
+// brailleDots maps a position within a braille character to its dot bit.
+//
+//nolint:gochecknoglobals
+var brailleDots = [brailleHeight][brailleWidth]uint8{
+	{0x01, 0x08},
+	{0x02, 0x10},
+	{0x04, 0x20},
+	{0x40, 0x80},
+}
+
+// RenderBraille writes the cells within rect using braille patterns, where
+// each character holds 2x4 dots and each dot shows a square of 2^level cells.
+// The viewport is aligned down to whole characters.
+func (n *Node) RenderBraille(buf *bytes.Buffer, rect image.Rectangle, level uint8) {
+	char := image.Pt(brailleWidth<<level, brailleHeight<<level)
+	size := image.Pt(
+		max(rect.Dx()+char.X-1, 0)/char.X,
+		max(rect.Dy()+char.Y-1, 0)/char.Y,
+	)
+	if size.Y == 0 {
+		return
+	}
+	origin := image.Pt(
+		rect.Min.X>>level&^(brailleWidth-1),
+		rect.Min.Y>>level&^(brailleHeight-1),
+	)
+	v := newViewport(origin, image.Pt(size.X*brailleWidth, size.Y*brailleHeight), level)
+	n.walk(v, &brailleWriter{
+		buf:    buf,
+		level:  level,
+		size:   size,
+		dots:   make([]uint8, size.X),
+		values: make([]int, size.X),
+	})
+}
+
+// brailleWriter accumulates four rows of blocks into a row of braille characters.
+type brailleWriter struct {
+	buf     *bytes.Buffer
+	level   uint8
+	size    image.Point
+	row     int
+	pending bool
+	dots    []uint8
+	values  []int
+}
+
+func (w *brailleWriter) writeRow(row int, cells []blockCell) {
+	charRow := row / brailleHeight
+	if w.pending && charRow != w.row {
+		w.flush()
+	}
+	for w.row < charRow {
+		w.flush()
+	}
+	w.pending = true
+	for _, c := range cells {
+		col := c.col / brailleWidth
+		w.dots[col] |= brailleDots[row%brailleHeight][c.col%brailleWidth]
+		w.values[col] += c.block.value
+	}
+}
+
+func (w *brailleWriter) finish() {
+	for w.row < w.size.Y {
+		w.flush()
+	}
+}
+
+// flush writes the current row of characters, grouping runs of the same color.
+func (w *brailleWriter) flush() {
+	var run strings.Builder
+	runColor := -1
+	writeRun := func() {
+		if run.Len() == 0 {
+			return
+		}
+		if runColor < 0 {
+			w.buf.WriteString(run.String())
+		} else {
+			w.buf.WriteString(colors[runColor].Render(run.String()))
+		}
+		run.Reset()
+	}
+
+	for i, dots := range w.dots {
+		color := -1
+		r := ' '
+		if dots != 0 {
+			r = brailleBlank + rune(dots)
+			color = brailleColor(w.values[i], w.level)
+		}
+		if color != runColor {
+			writeRun()
+			runColor = color
+		}
+		run.WriteRune(r)
+		w.dots[i], w.values[i] = 0, 0
+	}
+	writeRun()
+	w.buf.WriteByte('\n')
+	w.row++
+	w.pending = false
+}
+
+// brailleColor returns the color for a character based on the population of its dots.
+func brailleColor(value int, level uint8) int {
+	if level == 0 {
+		return len(colors) - 1
+	}
+	c := value * (len(colors) - 1) / (brailleWidth * brailleHeight << (level + 1))
+	return min(c, len(colors)-1)
+}
@@ -0,0 +1,69 @@
+package quadtree
+
+import (
+	"bytes"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNode_RenderBraille(t *testing.T) {
+	glider := Empty(5).
+		Set(image.Pt(1, 0), 1).
+		Set(image.Pt(2, 1), 1).
+		Set(image.Pt(0, 2), 1).
+		Set(image.Pt(1, 2), 1).
+		Set(image.Pt(2, 2), 1)
+
+	tests := []struct {
+		name  string
+		node  *Node
+		rect  image.Rectangle
+		level uint8
+		want  string
+	}{
+		{"empty", Empty(5), image.Rect(0, 0, 4, 8), 0, "  \n  \n"},
+		{"glider", glider, image.Rect(0, 0, 6, 4), 0, "⠬⠆ \n"},
+		{"glider unaligned", glider, image.Rect(1, 1, 5, 4), 0, "⠬⠆\n"},
+		{"glider level 1", glider, image.Rect(0, 0, 4, 8), 1, "⠛\n"},
+		{"outside tree", glider, image.Rect(100, 100, 102, 104), 0, " \n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.node.RenderBraille(&buf, tt.rect, tt.level)
+			assert.Equal(t, tt.want, ansi.Strip(buf.String()))
+		})
+	}
+
+	t.Run("random", func(t *testing.T) {
+		node, cells := treeWithRandomPattern(LeafLevel + 3)
+		rect := image.Rect(-30, -28, 34, 32)
+		var want strings.Builder
+		for y := rect.Min.Y; y < rect.Max.Y; y += brailleHeight {
+			for x := rect.Min.X; x < rect.Max.X; x += brailleWidth {
+				var dots uint8
+				for dy := range brailleHeight {
+					for dx := range brailleWidth {
+						if cells[image.Pt(x+dx, y+dy)] != 0 {
+							dots |= brailleDots[dy][dx]
+						}
+					}
+				}
+				if dots == 0 {
+					want.WriteRune(' ')
+				} else {
+					want.WriteRune(brailleBlank + rune(dots))
+				}
+			}
+			want.WriteByte('\n')
+		}
+
+		var buf bytes.Buffer
+		node.RenderBraille(&buf, rect, 0)
+		assert.Equal(t, want.String(), ansi.Strip(buf.String()))
+	})
+}
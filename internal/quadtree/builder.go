@@ -0,0 +1,154 @@
+package quadtree
+
+import (
+	"errors"
+	"fmt"
+	"image"
+)
+
+// Builder collects live cells and builds a tree bottom-up in a single pass,
+// which is much faster than calling Set for every cell of a large pattern.
+type Builder struct {
+	leaves map[image.Point]uint64
+}
+
+func NewBuilder() *Builder {
+	return &Builder{leaves: make(map[image.Point]uint64)}
+}
+
+// Set marks the cell at p as alive.
+func (b *Builder) Set(p image.Point) error {
+	return b.SetRun(p, 1)
+}
+
+// SetRun marks n consecutive cells starting at p and extending to the right as alive.
+func (b *Builder) SetRun(p image.Point, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if !RunInBounds(p, n) {
+		return fmt.Errorf("%w: %s", ErrUniverseOverflow, p)
+	}
+
+	key := image.Pt(0, p.Y>>LeafLevel)
+	shift := (p.Y & (leafWidth - 1)) * leafWidth
+	for n > 0 {
+		x := p.X & (leafWidth - 1)
+		count := min(n, leafWidth-x)
+		key.X = p.X >> LeafLevel
+		b.leaves[key] |= (uint64(1)<<count - 1) << (shift + x)
+		p.X += count
+		n -= count
+	}
+	return nil
+}
+
+// Node builds the smallest tree which contains every live cell. Leaves are
+// paired into parents level by level until only the four nodes around the
+// origin remain.
+func (b *Builder) Node() *Node {
+	nodes := make(map[image.Point]*Node, len(b.leaves))
+	for key, bits := range b.leaves {
+		if bits != 0 {
+			nodes[key] = leaf(bits)
+		}
+	}
+
+	level := uint8(LeafLevel)
+	for {
+		var centered bool
+		nodes, centered = buildParents(nodes, level)
+		level++
+		if centered {
+			return nodes[image.Point{}]
+		}
+	}
+}
+
+// buildParents combines the nodes of a level into their parents. Keys are the
+// node's position in units of its width. If every node was adjacent to the
+// origin, the parent is the root and is stored at the zero key.
+func buildParents(nodes map[image.Point]*Node, level uint8) (map[image.Point]*Node, bool) {
+	centered := true
+	grouped := make(map[image.Point]*Children, len(nodes)/2+1)
+	for key, n := range nodes {
+		if key.X < -1 || key.Y < -1 || key.X > 0 || key.Y > 0 {
+			centered = false
+		}
+		parent := image.Pt(key.X>>1, key.Y>>1)
+		c, ok := grouped[parent]
+		if !ok {
+			c = &Children{}
+			grouped[parent] = c
+		}
+		switch key.X&1 | key.Y&1<<1 {
+		case 0:
+			c.NW = n
+		case 1:
+			c.NE = n
+		case 2:
+			c.SW = n
+		default:
+			c.SE = n
+		}
+	}
+
+	if centered {
+		// The four nodes around the origin span keys -1 and 0, which belong to
+		// different parents, so they are combined directly into the root.
+		c := Children{
+			NW: nodes[image.Pt(-1, -1)],
+			NE: nodes[image.Pt(0, -1)],
+			SW: nodes[image.Pt(-1, 0)],
+			SE: nodes[image.Pt(0, 0)],
+		}
+		return map[image.Point]*Node{{}: newParent(c, level)}, true
+	}
+
+	parents := make(map[image.Point]*Node, len(grouped))
+	for key, c := range grouped {
+		parents[key] = newParent(*c, level)
+	}
+	return parents, false
+}
+
+// newParent returns the node with the given children, replacing missing
+// children with empty nodes of the given level.
+func newParent(c Children, level uint8) *Node {
+	e := Empty(level)
+	for _, child := range []**Node{&c.NW, &c.NE, &c.SW, &c.SE} {
+		if *child == nil {
+			*child = e
+		}
+	}
+	return memoizedNew.Call(c)
+}
+
+// Leaf returns the leaf for a bitboard, where bit y*8+x holds the cell at
+// (x, y) relative to the leaf's top-left corner.
+func Leaf(b uint64) *Node {
+	return leaf(b)
+}
+
+// Bits returns the bitboard of a leaf. It is zero for nodes above LeafLevel.
+func (n *Node) Bits() uint64 {
+	return n.bits
+}
+
+var ErrLevelMismatch = errors.New("children must have the same level")
+
+// Join returns the node with the given children. Nodes are shared, so
+// repeated subtrees take no extra memory.
+func Join(c Children) (*Node, error) {
+	if c.NW == nil || c.NE == nil || c.SW == nil || c.SE == nil {
+		return nil, fmt.Errorf("%w: missing child", ErrLevelMismatch)
+	}
+	level := c.NW.level
+	if c.NE.level != level || c.SW.level != level || c.SE.level != level {
+		return nil, fmt.Errorf("%w: %d, %d, %d, %d", ErrLevelMismatch, level, c.NE.level, c.SW.level, c.SE.level)
+	}
+	if level >= MaxLevel {
+		return nil, fmt.Errorf("%w: level %d", ErrUniverseOverflow, level+1)
+	}
+	return memoizedNew.Call(c), nil
+}
@@ -0,0 +1,77 @@
+package quadtree
+
+import (
+	"image"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		assert.Equal(t, Empty(LeafLevel+1), NewBuilder().Node())
+	})
+
+	t.Run("matches set", func(t *testing.T) {
+		want, cells := treeWithRandomPattern(LeafLevel + 3)
+		b := NewBuilder()
+		for p, v := range cells {
+			if v != 0 {
+				require.NoError(t, b.Set(p))
+			}
+		}
+		got := b.Node()
+		for got.level < want.level {
+			got = mustNode(got.grow())
+		}
+		assert.Same(t, want, got)
+	})
+
+	t.Run("far apart", func(t *testing.T) {
+		points := []image.Point{{-1 << 40, 3}, {1<<40 + 5, -1 << 33}, {0, 0}, {-1, -1}}
+		want := Empty(1)
+		b := NewBuilder()
+		for _, p := range points {
+			want = mustNode(want.GrowToFit(p)).Set(p, 1)
+			require.NoError(t, b.Set(p))
+		}
+		// Nodes above level 16 are not memoized, so they are compared by value.
+		assert.Equal(t, want, b.Node())
+	})
+
+	t.Run("run across leaves", func(t *testing.T) {
+		b := NewBuilder()
+		require.NoError(t, b.SetRun(image.Pt(-13, 2), 30))
+		got := b.Node()
+		assert.Equal(t, 30, got.value)
+		assert.Equal(t, image.Rect(-13, 2, 17, 3), got.FilledCoords())
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		b := NewBuilder()
+		require.ErrorIs(t, b.Set(image.Pt(1<<62, 0)), ErrUniverseOverflow)
+		require.ErrorIs(t, b.Set(image.Pt(0, -1<<62-1)), ErrUniverseOverflow)
+		require.ErrorIs(t, b.SetRun(image.Pt(1<<61, 0), 1<<61+1), ErrUniverseOverflow)
+		require.ErrorIs(t, b.SetRun(image.Pt(-1<<62+2, 0), math.MaxInt), ErrUniverseOverflow)
+	})
+}
+
+func TestJoin(t *testing.T) {
+	l := Leaf(0x0102)
+	assert.Equal(t, uint64(0x0102), l.Bits())
+	assert.Same(t, emptyLeaf, Leaf(0))
+
+	n, err := Join(Children{NW: l, NE: emptyLeaf, SW: emptyLeaf, SE: l})
+	require.NoError(t, err)
+	assert.Equal(t, uint8(LeafLevel+1), n.Level())
+	assert.Equal(t, 4, n.Value())
+	assert.Same(t, l, n.NW)
+
+	_, err = Join(Children{NW: n, NE: l, SW: l, SE: l})
+	require.ErrorIs(t, err, ErrLevelMismatch)
+
+	_, err = Join(Children{NW: l, NE: l, SW: l})
+	require.ErrorIs(t, err, ErrLevelMismatch)
+}
@@ -13,15 +13,23 @@ var (
 			return n.value == 0 || n.level <= 16
 		}),
 	)
+	memoizedLeaf  = memoizer.New(newLeaf)
 	memoizedEmpty = memoizer.New(Empty)
 )
 
 func ResetCache() {
 	memoizedNew.Reset()
+	memoizedLeaf.Reset()
 	memoizedEmpty.Reset()
 	runtime.GC()
 }
 
 func SetMaxCache(n int) {
 	memoizer.WithMax[Children, *Node](n)(memoizedNew)
+	memoizer.WithMax[uint64, *Node](n)(memoizedLeaf)
+}
+
+func cleanupCache() {
+	memoizedNew.Cleanup()
+	memoizedLeaf.Cleanup()
 }
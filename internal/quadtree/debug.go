@@ -1,12 +1,14 @@
 package quadtree
 
 import (
+	"math/big"
+
 	"gabe565.com/cli-of-life/internal/memoizer"
 )
 
 type Stats struct {
 	Steps      int
-	Generation uint64
+	Generation *big.Int
 	Level      int
 	Population int
 	memoizer.Stats
@@ -2,12 +2,15 @@ package quadtree
 
 import (
 	"image"
-	"slices"
 
+	"gabe565.com/cli-of-life/internal/memoizer"
 	"gabe565.com/cli-of-life/internal/rule"
 )
 
 func (n *Node) centeredSubnode() *Node {
+	if n.level == LeafLevel+1 {
+		return leaf(leafWindow(n.NW.bits, n.NE.bits, n.SW.bits, n.SE.bits, 4, 4))
+	}
 	return memoizedNew.Call(Children{
 		NW: n.NW.SE,
 		NE: n.NE.SW,
@@ -17,6 +20,9 @@ func (n *Node) centeredSubnode() *Node {
 }
 
 func (n *Node) centeredNHorizontal() *Node {
+	if n.level == LeafLevel+2 {
+		return memoizedNew.Call(Children{NW: n.NW.NE, NE: n.NE.NW, SW: n.NW.SE, SE: n.NE.SW}).centeredSubnode()
+	}
 	return memoizedNew.Call(Children{
 		NW: n.NW.NE.SE,
 		NE: n.NE.NW.SW,
@@ -26,6 +32,9 @@ func (n *Node) centeredNHorizontal() *Node {
 }
 
 func (n *Node) centeredSHorizontal() *Node {
+	if n.level == LeafLevel+2 {
+		return memoizedNew.Call(Children{NW: n.SW.NE, NE: n.SE.NW, SW: n.SW.SE, SE: n.SE.SW}).centeredSubnode()
+	}
 	return memoizedNew.Call(Children{
 		NW: n.SW.NE.SE,
 		NE: n.SE.NW.SW,
@@ -35,6 +44,9 @@ func (n *Node) centeredSHorizontal() *Node {
 }
 
 func (n *Node) centeredWVertical() *Node {
+	if n.level == LeafLevel+2 {
+		return memoizedNew.Call(Children{NW: n.NW.SW, NE: n.NW.SE, SW: n.SW.NW, SE: n.SW.NE}).centeredSubnode()
+	}
 	return memoizedNew.Call(Children{
 		NW: n.NW.SW.SE,
 		NE: n.NW.SE.SW,
@@ -44,6 +56,9 @@ func (n *Node) centeredWVertical() *Node {
 }
 
 func (n *Node) centeredEVertical() *Node {
+	if n.level == LeafLevel+2 {
+		return memoizedNew.Call(Children{NW: n.NE.SW, NE: n.NE.SE, SW: n.SE.NW, SE: n.SE.NE}).centeredSubnode()
+	}
 	return memoizedNew.Call(Children{
 		NW: n.NE.SW.SE,
 		NE: n.NE.SE.SW,
@@ -53,6 +68,9 @@ func (n *Node) centeredEVertical() *Node {
 }
 
 func (n *Node) centeredSubSubnode() *Node {
+	if n.level == LeafLevel+2 {
+		return memoizedNew.Call(Children{NW: n.NW.SE, NE: n.NE.SW, SW: n.SW.NE, SE: n.SE.NW}).centeredSubnode()
+	}
 	return memoizedNew.Call(Children{
 		NW: n.NW.SE.SE,
 		NE: n.NE.SW.SW,
@@ -61,43 +79,61 @@ func (n *Node) centeredSubSubnode() *Node {
 	})
 }
 
-func (n *Node) slowSimulation(r *rule.Rule) *Node {
-	if n.level != 2 {
-		panic("slowSimulation only possible for quadtree of size 2")
-	}
-	var b uint16
-	for y := -2; y < 2; y++ {
-		for x := -2; x < 2; x++ {
-			b = (b << 1) + uint16(n.Get(image.Pt(x, y), 0).value) //nolint:gosec
+// ruleTable maps every 4x4 neighborhood to the next state of its center 2x2
+// cells. Bit y*4+x of the index is the cell at (x, y); bits 0-3 of the value
+// are the center cells at (1, 1), (2, 1), (1, 2) and (2, 2).
+type ruleTable [1 << 16]uint8
+
+type ruleMask struct {
+	born, survive uint16
+}
+
+//nolint:gochecknoglobals
+var memoizedRuleTable = memoizer.New(newRuleTable)
+
+func newRuleTable(m ruleMask) *ruleTable {
+	var t ruleTable
+	for i := range t {
+		var out uint8
+		for j, c := range []image.Point{{1, 1}, {2, 1}, {1, 2}, {2, 2}} {
+			var neighbors int
+			for y := c.Y - 1; y <= c.Y+1; y++ {
+				for x := c.X - 1; x <= c.X+1; x++ {
+					if (x != c.X || y != c.Y) && i>>(y*4+x)&1 != 0 {
+						neighbors++
+					}
+				}
+			}
+			mask := m.born
+			if i>>(c.Y*4+c.X)&1 != 0 {
+				mask = m.survive
+			}
+			if mask>>neighbors&1 != 0 {
+				out |= 1 << j
+			}
 		}
+		t[i] = out
 	}
-	return memoizedNew.Call(Children{NW: oneGen(b>>5, r), NE: oneGen(b>>4, r), SW: oneGen(b>>1, r), SE: oneGen(b, r)})
+	return &t
 }
 
-func oneGen(bitmask uint16, r *rule.Rule) *Node {
-	if bitmask == 0 {
-		return deadLeaf
-	}
-	self := (bitmask >> 5) & 1
-	bitmask &= 0b0111_0101_0111
-	var neighbors int
-	for bitmask != 0 {
-		neighbors++
-		bitmask &= bitmask - 1
+func lookupRuleTable(r *rule.Rule) *ruleTable {
+	var m ruleMask
+	for _, v := range r.Born {
+		m.born |= 1 << v
 	}
-	switch {
-	case self == 0 && slices.Contains(r.Born, neighbors), self != 0 && slices.Contains(r.Survive, neighbors):
-		return aliveLeaf
+	for _, v := range r.Survive {
+		m.survive |= 1 << v
 	}
-	return deadLeaf
+	return memoizedRuleTable.Call(m)
 }
 
 func (n *Node) step(r *rule.Rule) *Node {
 	switch {
 	case n.next != nil:
 		return n.next
-	case n.level == 2:
-		n.next = n.slowSimulation(r)
+	case n.level == LeafLevel+1:
+		n.next = stepLeaves(n, lookupRuleTable(r))
 		return n.next
 	}
 
@@ -2,7 +2,13 @@ package quadtree
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"image"
+	"iter"
+	"math/big"
+	"sync"
+	"sync/atomic"
 
 	"gabe565.com/cli-of-life/internal/rule"
 )
@@ -10,86 +16,341 @@ import (
 const DefaultLevel = 9
 
 func New() *Gosper {
-	return &Gosper{
+	g := &Gosper{
 		cells: Empty(DefaultLevel),
 	}
+	g.publish()
+	return g
 }
 
+// Gosper is a mutable universe. Writes are serialized with a mutex, and every
+// change publishes an immutable Snapshot so that readers never wait on the
+// simulation.
+//
+// Coordinates passed to and returned by its methods are relative to the tree's
+// center. The absolute position of that center is kept in an arbitrary-precision
+// offset, so a pattern can travel further than an int can count.
 type Gosper struct {
-	resetCells *Node
-	cells      *Node
-	generation uint64
+	resetCells  *Node
+	resetGen    big.Int
+	resetOffset BigPoint
+	cells       *Node
+	generation  big.Int
+	offset      BigPoint
+	steps       int
+	// epoch changes whenever the cells are replaced, so that a step which
+	// started before a reset stops instead of stepping the new cells.
+	epoch      uint64
+	stochastic *Stochastic
+	mu         sync.Mutex
+	snapshot   atomic.Pointer[Snapshot]
+}
+
+// Snapshot is an immutable view of the universe at a single generation.
+type Snapshot struct {
+	root       *Node
+	generation *big.Int
+	offset     BigPoint
 	steps      int
 }
 
+// publish stores a snapshot of the current state. It must be called with the
+// lock held.
+func (g *Gosper) publish() {
+	g.snapshot.Store(&Snapshot{
+		root:       g.cells,
+		generation: new(big.Int).Set(&g.generation),
+		offset:     g.offset,
+		steps:      g.steps,
+	})
+}
+
+// Snapshot returns the latest published state. It is safe to use concurrently
+// with stepping.
+func (g *Gosper) Snapshot() *Snapshot {
+	return g.snapshot.Load()
+}
+
+func (s *Snapshot) Root() *Node {
+	return s.root
+}
+
+func (s *Snapshot) Generation() *big.Int {
+	return new(big.Int).Set(s.generation)
+}
+
+// Offset returns the absolute position of the root's center. A cell at p in
+// the root is at Offset().Add(p).
+func (s *Snapshot) Offset() BigPoint {
+	return s.offset.Add(image.Point{})
+}
+
 func (g *Gosper) Get(p image.Point) bool {
-	w := g.cells.Width() / 2
+	root := g.Snapshot().root
+	w := root.halfWidth()
 	if p.X < -w || p.Y < -w || p.X >= w || p.Y >= w {
 		return false
 	}
-	return g.cells.Get(p, 0).value != 0
+	return root.Cell(p) != 0
+}
+
+var ErrUniverseOverflow = errors.New("universe would exceed maximum size")
+
+// InBounds reports whether p fits within a universe of MaxLevel.
+func InBounds(p image.Point) bool {
+	const w = 1 << (MaxLevel - 1)
+	return p.X >= -w && p.Y >= -w && p.X < w && p.Y < w
+}
+
+// RunInBounds reports whether a run of n cells starting at p and extending to
+// the right fits within a universe of MaxLevel.
+func RunInBounds(p image.Point, n int) bool {
+	const w = 1 << (MaxLevel - 1)
+	// Compare the last cell rather than the end, which may overflow.
+	return InBounds(p) && (n <= 0 || n-1 <= w-1-p.X)
 }
 
-func (g *Gosper) Set(p image.Point, v int) {
-	g.cells = g.cells.GrowToFit(p)
+func (g *Gosper) Set(p image.Point, v int) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if err := g.growToFit(p); err != nil {
+		return err
+	}
 	g.cells = g.cells.Set(p, v)
+	g.publish()
+	return nil
 }
 
-func (g *Gosper) Step(r *rule.Rule, steps uint64) {
-	memoizedNew.Cleanup()
+// ProgressFunc receives the number of generations completed out of the total.
+type ProgressFunc func(done, total uint64)
+
+var ErrReset = errors.New("universe was reset while stepping")
 
+// Step advances the universe by the given number of generations. The context
+// is checked between generations, and progress is called after each one if it
+// is not nil. The cells are recentered as they travel, so only a pattern which
+// spans more than MaxLevel stops stepping early with ErrUniverseOverflow. If
+// the universe is reset or replaced, stepping stops and ErrReset is returned.
+func (g *Gosper) Step(ctx context.Context, r *rule.Rule, steps uint64, progress ProgressFunc) error {
+	g.mu.Lock()
+	cleanupCache()
 	g.steps++
-	g.generation += steps
+	epoch := g.epoch
+	g.publish()
+	g.mu.Unlock()
+
+	for done := range steps {
+		if err := g.stepOnce(ctx, r, epoch); err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(done+1, steps)
+		}
+	}
+	return nil
+}
+
+// recenter moves the cells to the center of a smaller tree once the tree is
+// close enough to MaxLevel that stepping could overflow, adding the distance
+// they moved to the offset. It must be called with the lock held.
+func (g *Gosper) recenter() {
+	if g.cells.level < MaxLevel-1 {
+		return
+	}
+	cells, shift := g.cells.shrink(DefaultLevel)
+	g.cells = cells
+	g.offset = g.offset.Add(shift)
+}
+
+// stepOnce advances a single generation. The lock is only held for one
+// generation at a time so that rendering and edits are not blocked by long jumps.
+// Cancellation and resets are checked with the lock held, so no generation runs
+// after Reset returns.
+func (g *Gosper) stepOnce(ctx context.Context, r *rule.Rule, epoch uint64) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if g.epoch != epoch {
+		return ErrReset
+	}
 
-	for range steps {
-		if !g.cells.IsEdgesEmpty() {
-			g.cells = g.cells.grow()
+	g.recenter()
+	if g.stochastic != nil {
+		cells, err := g.stochastic.step(g.cells, r)
+		if err != nil {
+			return err
+		}
+		g.cells = cells
+	} else {
+		cells := g.cells
+		var err error
+		if !cells.IsEdgesEmpty() {
+			if cells, err = cells.grow(); err != nil {
+				return err
+			}
 		}
-		g.cells = g.cells.grow().step(r)
+		if cells, err = cells.grow(); err != nil {
+			return err
+		}
+		g.cells = cells.step(r)
 	}
+
+	g.generation.Add(&g.generation, big.NewInt(1))
+	g.publish()
+	return nil
 }
 
-func (g *Gosper) GrowToFit(p image.Point) {
-	g.cells = g.cells.GrowToFit(p)
+func (g *Gosper) GrowToFit(p image.Point) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.growToFit(p)
+}
+
+func (g *Gosper) growToFit(p image.Point) error {
+	cells, err := g.cells.GrowToFit(p)
+	if err != nil {
+		return err
+	}
+	g.cells = cells
+	g.publish()
+	return nil
 }
 
+// SetCells replaces the universe with the given tree, growing it to at least
+// DefaultLevel, and resets the generation count and offset.
+func (g *Gosper) SetCells(n *Node) {
+	for n.level < DefaultLevel {
+		// Growing below MaxLevel can't fail.
+		n, _ = n.grow()
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.cells = n
+	g.epoch++
+	g.steps = 0
+	g.generation.SetInt64(0)
+	g.offset = BigPoint{}
+	g.publish()
+}
+
+// SetOffset sets the absolute position of the tree's center, for patterns
+// which are placed beyond the range of an int.
+func (g *Gosper) SetOffset(p BigPoint) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.offset = p.Add(image.Point{})
+	g.publish()
+}
+
+// SetGeneration sets the generation count, for patterns which were saved
+// partway through a run.
+func (g *Gosper) SetGeneration(gen *big.Int) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.generation.Set(gen)
+	g.publish()
+}
+
+// SetReset stores the current cells, generation and offset as the state
+// restored by Reset.
 func (g *Gosper) SetReset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	g.resetCells = g.cells
+	g.resetGen.Set(&g.generation)
+	g.resetOffset = g.offset
 }
 
 func (g *Gosper) Reset() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
 	if g.resetCells != nil {
 		g.cells = g.resetCells
 	} else {
 		g.cells = Empty(DefaultLevel)
 	}
+	g.epoch++
 	g.steps = 0
-	g.generation = 0
+	g.generation.Set(&g.resetGen)
+	g.offset = g.resetOffset
+	if g.stochastic != nil {
+		g.stochastic.Reset()
+	}
+	g.publish()
+}
+
+// SetStochastic switches the universe to the probabilistic direct-simulation
+// engine. Passing nil restores Hashlife stepping.
+func (g *Gosper) SetStochastic(s *Stochastic) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.stochastic = s
 }
 
 func (g *Gosper) FilledCoords() image.Rectangle {
-	return g.cells.FilledCoords()
+	return g.Snapshot().root.FilledCoords()
+}
+
+// Offset returns the absolute position of the tree's center.
+func (g *Gosper) Offset() BigPoint {
+	return g.Snapshot().Offset()
 }
 
 func (g *Gosper) IsEmpty() bool {
-	return g.cells.IsEmpty()
+	return g.Snapshot().root.IsEmpty()
 }
 
 func (g *Gosper) Level() uint8 {
-	return g.cells.level
+	return g.Snapshot().root.level
 }
 
 func (g *Gosper) Stats() Stats {
-	s := g.cells.Stats()
-	s.Generation = g.generation
-	s.Steps = g.steps
-	return s
+	return g.Snapshot().Stats()
+}
+
+func (s *Snapshot) Stats() Stats {
+	stats := s.root.Stats()
+	stats.Generation = s.Generation()
+	stats.Steps = s.steps
+	return stats
 }
 
 func (g *Gosper) Render(buf *bytes.Buffer, r image.Rectangle, level uint8) {
-	g.cells.Render(buf, r, level)
+	g.Snapshot().root.Render(buf, r, level)
+}
+
+// All returns an iterator over the coordinates of every live cell in
+// row-major order. It walks the snapshot taken when iteration starts.
+func (g *Gosper) All() iter.Seq[image.Point] {
+	return func(yield func(image.Point) bool) {
+		g.Snapshot().root.All()(yield)
+	}
+}
+
+// Runs returns an iterator over the horizontal runs of live cells in
+// row-major order. It walks the snapshot taken when iteration starts.
+func (g *Gosper) Runs() iter.Seq[Run] {
+	return func(yield func(Run) bool) {
+		g.Snapshot().root.Runs()(yield)
+	}
+}
+
+func (g *Gosper) RenderBraille(buf *bytes.Buffer, r image.Rectangle, level uint8) {
+	g.Snapshot().root.RenderBraille(buf, r, level)
+}
+
+func (g *Gosper) RenderZoomed(buf *bytes.Buffer, r image.Rectangle, scale int, grid bool) {
+	g.Snapshot().root.RenderZoomed(buf, r, scale, grid)
+}
+
+func (g *Gosper) RenderImage(r image.Rectangle, level uint8) *image.Paletted {
+	return g.Snapshot().root.RenderImage(r, level)
 }
 
 func (g *Gosper) ToSlice() [][]int {
-	return g.cells.ToSlice()
+	return g.Snapshot().root.ToSlice()
 }
@@ -0,0 +1,129 @@
+package quadtree
+
+import (
+	"context"
+	"image"
+	"math/big"
+	"testing"
+
+	"gabe565.com/cli-of-life/internal/rule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGosper_Set(t *testing.T) {
+	t.Run("in bounds", func(t *testing.T) {
+		g := New()
+		require.NoError(t, g.Set(image.Pt(1<<62-1, -1<<62), 1))
+		assert.True(t, g.Get(image.Pt(1<<62-1, -1<<62)))
+		assert.EqualValues(t, MaxLevel, g.Level())
+		assert.Equal(t, image.Rect(1<<62-1, -1<<62, 1<<62, 1-1<<62), g.FilledCoords())
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		g := New()
+		require.ErrorIs(t, g.Set(image.Pt(1<<62, 0), 1), ErrUniverseOverflow)
+		assert.True(t, g.IsEmpty())
+	})
+}
+
+func TestGosper_Step(t *testing.T) {
+	r := rule.GameOfLife()
+
+	t.Run("generation", func(t *testing.T) {
+		g := gliderGosper(t)
+		require.NoError(t, g.Step(t.Context(), &r, 3, nil))
+		require.NoError(t, g.Step(t.Context(), &r, 2, nil))
+		assert.Equal(t, big.NewInt(5), g.Stats().Generation)
+		g.Reset()
+		assert.Equal(t, big.NewInt(0), g.Stats().Generation)
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		g := New()
+		require.NoError(t, g.Set(image.Pt(-1<<62, -1<<62), 1))
+		require.NoError(t, g.Set(image.Pt(1<<62-1, 1<<62-1), 1))
+		require.ErrorIs(t, g.Step(t.Context(), &r, 1, nil), ErrUniverseOverflow)
+		assert.Equal(t, big.NewInt(0), g.Stats().Generation)
+	})
+
+	t.Run("recenter", func(t *testing.T) {
+		// A glider at the edge of the universe, and one at the origin to compare against.
+		edge := image.Pt(1<<62-8, 1<<62-8)
+		g, want := New(), gliderGosper(t)
+		for p := range want.All() {
+			require.NoError(t, g.Set(p.Add(edge), 1))
+		}
+		g.SetReset()
+		assert.EqualValues(t, MaxLevel, g.Level())
+
+		require.NoError(t, g.Step(t.Context(), &r, 40, nil))
+		require.NoError(t, want.Step(t.Context(), &r, 40, nil))
+		assert.Less(t, g.Level(), uint8(MaxLevel))
+		assert.False(t, g.Offset().IsZero())
+
+		// The glider has moved past the edge, so compare absolute positions.
+		var got, expected []string
+		for p := range g.All() {
+			got = append(got, g.Offset().Add(p).String())
+		}
+		for p := range want.All() {
+			expected = append(expected, BigPt(1<<62-8, 1<<62-8).Add(p).String())
+		}
+		assert.Equal(t, expected, got)
+
+		g.Reset()
+		assert.True(t, g.Offset().IsZero())
+		assert.True(t, g.Get(edge.Add(image.Pt(1, 0))))
+	})
+}
+
+func TestGosper_SetGeneration(t *testing.T) {
+	r := rule.GameOfLife()
+	g := gliderGosper(t)
+	g.SetGeneration(big.NewInt(100))
+	g.SetReset()
+	require.NoError(t, g.Step(t.Context(), &r, 4, nil))
+	assert.Equal(t, big.NewInt(104), g.Stats().Generation)
+	g.Reset()
+	assert.Equal(t, big.NewInt(100), g.Stats().Generation)
+}
+
+func TestGosper_Step_context(t *testing.T) {
+	r := rule.GameOfLife()
+
+	t.Run("progress", func(t *testing.T) {
+		g := gliderGosper(t)
+		var calls []uint64
+		require.NoError(t, g.Step(t.Context(), &r, 3, func(done, total uint64) {
+			assert.EqualValues(t, 3, total)
+			calls = append(calls, done)
+		}))
+		assert.Equal(t, []uint64{1, 2, 3}, calls)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		g := gliderGosper(t)
+		ctx, cancel := context.WithCancel(t.Context())
+		err := g.Step(ctx, &r, 100, func(done, _ uint64) {
+			if done == 2 {
+				cancel()
+			}
+		})
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, big.NewInt(2), g.Stats().Generation)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		g := gliderGosper(t)
+		g.SetReset()
+		err := g.Step(t.Context(), &r, 100, func(done, _ uint64) {
+			if done == 2 {
+				g.Reset()
+			}
+		})
+		require.ErrorIs(t, err, ErrReset)
+		assert.Equal(t, big.NewInt(0), g.Stats().Generation)
+		assert.Equal(t, gliderGosper(t).ToSlice(), g.ToSlice())
+	})
+}
@@ -0,0 +1,37 @@
+package quadtree
+
+import (
+	"image"
+)
+
+// RenderImage returns an image of the cells within rect with one pixel per
+// square of 2^level cells, shaded by population in the same way as Render.
+// Empty pixels are transparent.
+func (n *Node) RenderImage(rect image.Rectangle, level uint8) *image.Paletted {
+	skip := 1 << level
+	size := image.Pt(
+		max(rect.Dx()+skip-1, 0)>>level,
+		max(rect.Dy()+skip-1, 0)>>level,
+	)
+	img := image.NewPaletted(image.Rectangle{Max: size}, palette)
+	if size.X == 0 || size.Y == 0 {
+		return img
+	}
+	v := newViewport(image.Pt(rect.Min.X>>level, rect.Min.Y>>level), size, level)
+	n.walk(v, imageWriter{img: img, level: level})
+	return img
+}
+
+type imageWriter struct {
+	img   *image.Paletted
+	level uint8
+}
+
+func (w imageWriter) writeRow(row int, cells []blockCell) {
+	for _, c := range cells {
+		// Index 0 is transparent, so colors are offset by one.
+		w.img.SetColorIndex(c.col, row, uint8(1+shade(c.block.value, w.level))) //nolint:gosec
+	}
+}
+
+func (w imageWriter) finish() {}
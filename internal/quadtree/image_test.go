@@ -0,0 +1,45 @@
+package quadtree
+
+import (
+	"image"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNode_RenderImage(t *testing.T) {
+	t.Run("level 0", func(t *testing.T) {
+		node, cells := treeWithRandomPattern(LeafLevel + 3)
+		rect := image.Rect(-30, -27, 34, 32)
+		img := node.RenderImage(rect, 0)
+		assert.Equal(t, rect.Size(), img.Bounds().Size())
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				want := uint8(0)
+				if cells[image.Pt(x, y)] != 0 {
+					want = uint8(len(palette) - 1)
+				}
+				assert.Equal(t, want, img.ColorIndexAt(x-rect.Min.X, y-rect.Min.Y), "(%d, %d)", x, y)
+			}
+		}
+	})
+
+	t.Run("level 1", func(t *testing.T) {
+		node := Empty(5).
+			Set(image.Pt(0, 0), 1).
+			Set(image.Pt(2, 0), 1).
+			Set(image.Pt(3, 0), 1).
+			Set(image.Pt(2, 1), 1).
+			Set(image.Pt(3, 1), 1)
+		img := node.RenderImage(image.Rect(0, 0, 6, 2), 1)
+		assert.Equal(t, image.Pt(3, 1), img.Bounds().Size())
+		assert.Equal(t, uint8(1+shade(1, 1)), img.ColorIndexAt(0, 0))
+		assert.Equal(t, uint8(1+shade(4, 1)), img.ColorIndexAt(1, 0))
+		assert.Equal(t, uint8(0), img.ColorIndexAt(2, 0))
+	})
+
+	t.Run("empty rect", func(t *testing.T) {
+		img := Empty(5).RenderImage(image.Rect(0, 0, 0, 4), 0)
+		assert.True(t, img.Bounds().Empty())
+	})
+}
@@ -0,0 +1,109 @@
+package quadtree
+
+import (
+	"image"
+	"iter"
+	"math/bits"
+)
+
+// Run is a horizontal run of live cells starting at (X, Y).
+type Run struct {
+	Y, X, Len int
+}
+
+// All returns an iterator over the coordinates of every live cell in row-major order.
+func (n *Node) All() iter.Seq[image.Point] {
+	return func(yield func(image.Point) bool) {
+		for run := range n.Runs() {
+			for x := run.X; x < run.X+run.Len; x++ {
+				if !yield(image.Pt(x, run.Y)) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Runs returns an iterator over the horizontal runs of live cells in
+// row-major order. The tree is walked once in horizontal strips, and empty
+// subtrees are skipped entirely, so memory use is proportional to the widest
+// strip of non-empty nodes rather than the pattern's area.
+func (n *Node) Runs() iter.Seq[Run] {
+	return func(yield func(Run) bool) {
+		if n.value == 0 {
+			return
+		}
+		w := n.halfWidth()
+		stripRuns(yield, []stripNode{{node: n, x: -w}}, -w)
+	}
+}
+
+// stripNode is a non-empty node within a horizontal strip, with the x
+// coordinate of its left edge.
+type stripNode struct {
+	node *Node
+	x    int
+}
+
+// stripRuns yields the runs of a strip of same-level nodes sorted by x whose
+// top edge is at y. It reports whether iteration should continue.
+func stripRuns(yield func(Run) bool, strip []stripNode, y int) bool {
+	level := strip[0].node.level
+	if level == LeafLevel {
+		return leafRuns(yield, strip, y)
+	}
+
+	w := 1 << (level - 1)
+	top := make([]stripNode, 0, 2*len(strip))
+	bottom := make([]stripNode, 0, 2*len(strip))
+	for _, s := range strip {
+		top = appendStrip(top, s.node.NW, s.x)
+		top = appendStrip(top, s.node.NE, s.x+w)
+		bottom = appendStrip(bottom, s.node.SW, s.x)
+		bottom = appendStrip(bottom, s.node.SE, s.x+w)
+	}
+	if len(top) != 0 && !stripRuns(yield, top, y) {
+		return false
+	}
+	if len(bottom) != 0 && !stripRuns(yield, bottom, y+w) {
+		return false
+	}
+	return true
+}
+
+func appendStrip(strip []stripNode, n *Node, x int) []stripNode {
+	if n.value == 0 {
+		return strip
+	}
+	return append(strip, stripNode{node: n, x: x})
+}
+
+// leafRuns yields the runs of a strip of leaves row by row, joining runs that
+// cross from one leaf into the next.
+func leafRuns(yield func(Run) bool, strip []stripNode, y int) bool {
+	for row := range leafWidth {
+		var run Run
+		for _, s := range strip {
+			line := uint16(s.node.bits >> (row * leafWidth) & 0xFF)
+			for line != 0 {
+				start := bits.TrailingZeros16(line)
+				length := bits.TrailingZeros16(^(line >> start))
+				line &^= (1<<length - 1) << start
+
+				x := s.x + start
+				if run.Len != 0 && run.X+run.Len == x {
+					run.Len += length
+					continue
+				}
+				if run.Len != 0 && !yield(run) {
+					return false
+				}
+				run = Run{Y: y + row, X: x, Len: length}
+			}
+		}
+		if run.Len != 0 && !yield(run) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package quadtree
+
+import (
+	"image"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNode_Runs(t *testing.T) {
+	tests := []struct {
+		name string
+		node *Node
+		want []Run
+	}{
+		{"empty", Empty(5), nil},
+		{
+			"glider",
+			Empty(5).
+				Set(image.Pt(1, 0), 1).
+				Set(image.Pt(2, 1), 1).
+				Set(image.Pt(0, 2), 1).
+				Set(image.Pt(1, 2), 1).
+				Set(image.Pt(2, 2), 1),
+			[]Run{{Y: 0, X: 1, Len: 1}, {Y: 1, X: 2, Len: 1}, {Y: 2, X: 0, Len: 3}},
+		},
+		{
+			"across leaves",
+			func() *Node {
+				node := Empty(6)
+				for x := -12; x < 20; x++ {
+					node = node.Set(image.Pt(x, -1), 1)
+				}
+				return node.Set(image.Pt(-20, 0), 1)
+			}(),
+			[]Run{{Y: -1, X: -12, Len: 32}, {Y: 0, X: -20, Len: 1}},
+		},
+		{
+			"far apart",
+			mustNode(Empty(1).GrowToFit(image.Pt(1<<40, 1<<40))).
+				Set(image.Pt(1<<40, 1<<40), 1).
+				Set(image.Pt(-5, -(1<<40)), 1),
+			[]Run{{Y: -(1 << 40), X: -5, Len: 1}, {Y: 1 << 40, X: 1 << 40, Len: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, slices.Collect(tt.node.Runs()))
+		})
+	}
+
+	t.Run("stops early", func(t *testing.T) {
+		node, _ := treeWithRandomPattern(LeafLevel + 2)
+		var count int
+		for range node.Runs() {
+			count++
+			break
+		}
+		assert.Equal(t, 1, count)
+	})
+}
+
+func TestNode_All(t *testing.T) {
+	node, cells := treeWithRandomPattern(LeafLevel + 3)
+	want := make([]image.Point, 0, len(cells))
+	for p := range cells {
+		want = append(want, p)
+	}
+	slices.SortFunc(want, comparePoints)
+	assert.Equal(t, want, slices.Collect(node.All()))
+}
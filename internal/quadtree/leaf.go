@@ -0,0 +1,139 @@
+package quadtree
+
+import (
+	"image"
+	"math/bits"
+)
+
+const (
+	// LeafLevel is the level of leaf nodes. Leaves store an 8x8 block of cells
+	// as a bitboard instead of having children.
+	LeafLevel = 3
+	leafWidth = 1 << LeafLevel
+
+	// Bit index y*8+x holds the cell at (x, y) relative to the leaf's top-left corner.
+	quadNW uint64 = 0x0F0F0F0F
+	quadNE uint64 = 0xF0F0F0F0
+	quadSW        = quadNW << 32
+	quadSE        = quadNE << 32
+)
+
+//nolint:gochecknoglobals
+var emptyLeaf = &Node{level: LeafLevel}
+
+func newLeaf(b uint64) *Node {
+	return &Node{
+		level: LeafLevel,
+		bits:  b,
+		value: bits.OnesCount64(b),
+	}
+}
+
+// leaf returns the canonical leaf for a bitboard.
+func leaf(b uint64) *Node {
+	if b == 0 {
+		return emptyLeaf
+	}
+	return memoizedLeaf.Call(b)
+}
+
+func leafBit(p image.Point) uint64 {
+	return 1 << (p.Y*leafWidth + p.X)
+}
+
+// blockMask returns the mask of a size x size square with its top-left corner at p.
+func blockMask(p image.Point, size int) uint64 {
+	row := uint64(1)<<size - 1
+	var mask uint64
+	for y := p.Y; y < p.Y+size; y++ {
+		mask |= row << (y*leafWidth + p.X)
+	}
+	return mask
+}
+
+// leafRow returns the 16-cell row y of the 16x16 square made of four leaves.
+func leafRow(nw, ne, sw, se uint64, y int) uint16 {
+	if y >= leafWidth {
+		nw, ne = sw, se
+		y -= leafWidth
+	}
+	shift := y * leafWidth
+	return uint16(nw>>shift&0xFF) | uint16(ne>>shift&0xFF)<<leafWidth
+}
+
+// leafWindow extracts the 8x8 window with its top-left corner at (dx, dy) from
+// the 16x16 square made of four leaves.
+func leafWindow(nw, ne, sw, se uint64, dx, dy int) uint64 {
+	var b uint64
+	for y := range leafWidth {
+		row := leafRow(nw, ne, sw, se, y+dy) >> dx & 0xFF
+		b |= uint64(row) << (y * leafWidth)
+	}
+	return b
+}
+
+// growLeaf wraps a leaf in a level 4 node with the leaf centered.
+func growLeaf(n *Node) *Node {
+	return memoizedNew.Call(Children{
+		NW: leaf(leafWindow(0, 0, 0, n.bits, 4, 4)),
+		NE: leaf(leafWindow(0, 0, n.bits, 0, 4, 4)),
+		SW: leaf(leafWindow(0, n.bits, 0, 0, 4, 4)),
+		SE: leaf(leafWindow(n.bits, 0, 0, 0, 4, 4)),
+	})
+}
+
+// leafQuadrants returns the population of each quadrant of a leaf.
+func leafQuadrants(b uint64) (int, int, int, int) {
+	return bits.OnesCount64(b & quadNW), bits.OnesCount64(b & quadNE),
+		bits.OnesCount64(b & quadSW), bits.OnesCount64(b & quadSE)
+}
+
+// stepLeaves advances the center 8x8 cells of a level 4 node by one
+// generation. Each 2x2 block of the result is looked up from the 4x4
+// neighborhood around it.
+func stepLeaves(n *Node, table *ruleTable) *Node {
+	var rows [2 * leafWidth]uint16
+	for y := range rows {
+		rows[y] = leafRow(n.NW.bits, n.NE.bits, n.SW.bits, n.SE.bits, y)
+	}
+
+	var b uint64
+	for by := range leafWidth / 2 {
+		y := 3 + 2*by
+		for bx := range leafWidth / 2 {
+			x := 3 + 2*bx
+			idx := rows[y]>>x&0xF |
+				(rows[y+1]>>x&0xF)<<4 |
+				(rows[y+2]>>x&0xF)<<8 |
+				(rows[y+3]>>x&0xF)<<12
+			out := uint64(table[idx])
+			shift := 2*by*leafWidth + 2*bx
+			b |= (out&0b11)<<shift | (out>>2&0b11)<<(shift+leafWidth)
+		}
+	}
+	return leaf(b)
+}
+
+// leafColumns returns a byte with a bit set for each column containing a live cell.
+func leafColumns(b uint64) uint8 {
+	b |= b >> 32
+	b |= b >> 16
+	b |= b >> 8
+	return uint8(b) //nolint:gosec
+}
+
+func leafMinX(b uint64) int {
+	return bits.TrailingZeros8(leafColumns(b))
+}
+
+func leafMaxX(b uint64) int {
+	return leafWidth - 1 - bits.LeadingZeros8(leafColumns(b))
+}
+
+func leafMinY(b uint64) int {
+	return bits.TrailingZeros64(b) / leafWidth
+}
+
+func leafMaxY(b uint64) int {
+	return (63 - bits.LeadingZeros64(b)) / leafWidth
+}
@@ -3,9 +3,11 @@ package quadtree
 import (
 	"fmt"
 	"image"
-	"math"
+	"math/bits"
 )
 
+// MaxLevel is the largest supported tree level. A tree at this level spans
+// coordinates from -2^62 to 2^62-1, so the edges of every node fit in an int.
 const MaxLevel = 63
 
 type Children struct {
@@ -19,6 +21,7 @@ func (c *Children) value() int {
 type Node struct {
 	Children
 	next  *Node
+	bits  uint64
 	level uint8
 	value int
 }
@@ -31,12 +34,6 @@ func (n *Node) Value() int {
 	return n.value
 }
 
-//nolint:gochecknoglobals
-var (
-	aliveLeaf = &Node{value: 1}
-	deadLeaf  = &Node{value: 0}
-)
-
 func newNode(children Children) *Node {
 	return &Node{
 		level:    children.NW.level + 1,
@@ -46,8 +43,8 @@ func newNode(children Children) *Node {
 }
 
 func Empty(level uint8) *Node {
-	if level == 0 || level+1 == 0 || level+2 == 0 {
-		return deadLeaf
+	if level <= LeafLevel || level+1 == 0 || level+2 == 0 {
+		return emptyLeaf
 	}
 	child := Empty(level - 1)
 	return memoizedNew.Call(Children{NW: child, NE: child, SW: child, SE: child})
@@ -57,12 +54,13 @@ func (n *Node) IsEmpty() bool {
 	return n.value == 0
 }
 
-func (n *Node) grow() *Node {
+// grow returns a node one level larger with n at its center.
+func (n *Node) grow() (*Node, error) {
 	switch {
 	case n.level >= MaxLevel:
-		panic(fmt.Sprint("QuadTree can't grow beyond level:", n.level))
-	case n.level == 0:
-		panic(fmt.Sprint("Can't grow baby tree of level:", n.level))
+		return nil, fmt.Errorf("%w: level %d", ErrUniverseOverflow, n.level+1)
+	case n.level == LeafLevel:
+		return growLeaf(n), nil
 	}
 
 	e := memoizedEmpty.Call(n.level - 1)
@@ -71,37 +69,81 @@ func (n *Node) grow() *Node {
 		NE: memoizedNew.Call(Children{NW: e, NE: e, SW: n.NE, SE: e}),
 		SW: memoizedNew.Call(Children{NW: e, NE: n.SW, SW: e, SE: e}),
 		SE: memoizedNew.Call(Children{NW: n.SE, NE: e, SW: e, SE: e}),
-	})
+	}), nil
 }
 
 func (n *Node) IsEdgesEmpty() bool {
+	if n.level == LeafLevel+1 {
+		return n.NW.bits&^quadSE == 0 && n.NE.bits&^quadSW == 0 &&
+			n.SW.bits&^quadNE == 0 && n.SE.bits&^quadNW == 0
+	}
 	return n.NW.NW.IsEmpty() && n.NW.NE.IsEmpty() && n.NE.NW.IsEmpty() &&
 		n.NE.NE.IsEmpty() && n.NE.SE.IsEmpty() && n.SE.NE.IsEmpty() &&
 		n.SE.SE.IsEmpty() && n.SE.SW.IsEmpty() && n.SW.SE.IsEmpty() &&
 		n.SW.SW.IsEmpty() && n.SW.NW.IsEmpty() && n.NW.SW.IsEmpty()
 }
 
-func (n *Node) GrowToFit(p image.Point) *Node {
-	w := n.Width() / 2
+// GrowToFit returns n grown until it contains p. If p is outside a universe
+// of MaxLevel, ErrUniverseOverflow is returned.
+func (n *Node) GrowToFit(p image.Point) (*Node, error) {
+	w := n.halfWidth()
 	for p.X < -w || p.Y < -w || p.X >= w || p.Y >= w {
-		n = n.grow()
-		w = n.Width() / 2
+		var err error
+		if n, err = n.grow(); err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrUniverseOverflow, p)
+		}
+		w = n.halfWidth()
 	}
-	return n
+	return n, nil
+}
+
+// shrink returns the smallest node, no smaller than minLevel, which holds
+// every live cell of n with the cells near its center. It also returns how far
+// the cells moved, so a point p in n is at p.Sub(shift) in the result.
+func (n *Node) shrink(minLevel uint8) (*Node, image.Point) {
+	var shift image.Point
+	for n.level > minLevel && n.level >= LeafLevel+2 {
+		// The 4x4 grid of grandchildren holds 9 overlapping squares one level
+		// down. Prefer the center one, so the cells don't move unless needed.
+		grid := [4][4]*Node{
+			{n.NW.NW, n.NW.NE, n.NE.NW, n.NE.NE},
+			{n.NW.SW, n.NW.SE, n.NE.SW, n.NE.SE},
+			{n.SW.NW, n.SW.NE, n.SE.NW, n.SE.NE},
+			{n.SW.SW, n.SW.SE, n.SE.SW, n.SE.SE},
+		}
+		q := 1 << (n.level - 2)
+		var found bool
+		for _, i := range [...]image.Point{
+			{1, 1}, {0, 1}, {2, 1}, {1, 0}, {1, 2}, {0, 0}, {2, 0}, {0, 2}, {2, 2},
+		} {
+			sub := memoizedNew.Call(Children{
+				NW: grid[i.Y][i.X], NE: grid[i.Y][i.X+1],
+				SW: grid[i.Y+1][i.X], SE: grid[i.Y+1][i.X+1],
+			})
+			if sub.value == n.value {
+				n = sub
+				shift = shift.Add(i.Sub(image.Pt(1, 1)).Mul(q))
+				found = true
+				break
+			}
+		}
+		if !found {
+			break
+		}
+	}
+	return n, shift
 }
 
 func (n *Node) Set(p image.Point, value int) *Node {
-	if n.level == 0 {
-		switch {
-		case p.X < -1, p.X > 0, p.Y < -1, p.Y > 0:
+	if n.level == LeafLevel {
+		p = p.Add(image.Pt(leafWidth/2, leafWidth/2))
+		if p.X < 0 || p.Y < 0 || p.X >= leafWidth || p.Y >= leafWidth {
 			panic(fmt.Sprintf("Reached leaf node with coordinates too big: (%d, %d)", p.X, p.Y))
-		case value == n.value:
-			return n
-		case value == 0:
-			return deadLeaf
-		default:
-			return aliveLeaf
 		}
+		if value == 0 {
+			return leaf(n.bits &^ leafBit(p))
+		}
+		return leaf(n.bits | leafBit(p))
 	}
 
 	w := 1 << (n.level - 2)
@@ -124,9 +166,18 @@ func (n *Node) children() []*Node {
 	return []*Node{n.SE, n.SW, n.NW, n.NE}
 }
 
+// Get returns the node at the given level which contains p. Levels below
+// LeafLevel return the leaf.
 func (n *Node) Get(p image.Point, level uint8) *Node {
-	if n == nil || n.level == level {
-		return n
+	n, _ = n.locate(p, level)
+	return n
+}
+
+// locate returns the node at the given level which contains p, along with p
+// relative to the node's center.
+func (n *Node) locate(p image.Point, level uint8) (*Node, image.Point) {
+	if n == nil || n.level <= level || n.level == LeafLevel {
+		return n, p
 	}
 
 	w := 1 << (n.level - 2)
@@ -134,21 +185,28 @@ func (n *Node) Get(p image.Point, level uint8) *Node {
 	case p.X >= 0:
 		switch {
 		case p.Y >= 0:
-			return n.SE.Get(p.Sub(image.Pt(w, w)), level)
+			return n.SE.locate(p.Sub(image.Pt(w, w)), level)
 		default:
-			return n.NE.Get(p.Add(image.Pt(-w, w)), level)
+			return n.NE.locate(p.Add(image.Pt(-w, w)), level)
 		}
 	case p.Y >= 0:
-		return n.SW.Get(p.Add(image.Pt(w, -w)), level)
+		return n.SW.locate(p.Add(image.Pt(w, -w)), level)
 	default:
-		return n.NW.Get(p.Add(image.Pt(w, w)), level)
+		return n.NW.locate(p.Add(image.Pt(w, w)), level)
 	}
 }
 
-type VisitCallback func(p image.Point, n *Node)
+// Cell returns the value of the cell at p.
+func (n *Node) Cell(p image.Point) int {
+	l, p := n.locate(p, LeafLevel)
+	p = p.Add(image.Pt(leafWidth/2, leafWidth/2))
+	return int(l.bits >> (p.Y*leafWidth + p.X) & 1)
+}
+
+type VisitCallback func(p image.Point)
 
 func (n *Node) Visit(callback VisitCallback) {
-	w := n.Width() / 2
+	w := n.halfWidth()
 	n.visit(image.Pt(-w, -w), callback)
 }
 
@@ -156,10 +214,13 @@ func (n *Node) visit(p image.Point, callback VisitCallback) {
 	switch {
 	case n.value == 0:
 		return
-	case n.level == 0:
-		callback(p, n)
+	case n.level == LeafLevel:
+		for b := n.bits; b != 0; b &= b - 1 {
+			i := bits.TrailingZeros64(b)
+			callback(p.Add(image.Pt(i%leafWidth, i/leafWidth)))
+		}
 	default:
-		w := n.Width() / 2
+		w := n.halfWidth()
 		n.SE.visit(p.Add(image.Pt(w, w)), callback)
 		n.SW.visit(p.Add(image.Pt(0, w)), callback)
 		n.NW.visit(p, callback)
@@ -167,30 +228,6 @@ func (n *Node) visit(p image.Point, callback VisitCallback) {
 	}
 }
 
-func (n *Node) FilledCoords() image.Rectangle {
-	x0, y0 := math.MaxInt, math.MaxInt
-	x1, y1 := math.MinInt, math.MinInt
-	n.Visit(func(p image.Point, _ *Node) {
-		if p.X < x0 {
-			x0 = p.X
-		}
-		if p.Y < y0 {
-			y0 = p.Y
-		}
-		if p.X > x1 {
-			x1 = p.X + 1
-		}
-		if p.Y > y1 {
-			y1 = p.Y + 1
-		}
-	})
-
-	if x0 == math.MaxInt || y0 == math.MaxInt || x1 == math.MinInt || y1 == math.MinInt {
-		return image.Rectangle{}
-	}
-	return image.Rect(x0, y0, x1, y1)
-}
-
 func (n *Node) ToSlice() [][]int {
 	coords := n.FilledCoords()
 	if coords.Empty() {
@@ -203,14 +240,22 @@ func (n *Node) ToSlice() [][]int {
 		result[i] = make([]int, size.X)
 	}
 
-	for y := coords.Min.Y; y < coords.Max.Y; y++ {
-		for x := coords.Min.X; x < coords.Max.X; x++ {
-			result[y-coords.Min.Y][x-coords.Min.X] = n.Get(image.Pt(x, y), 0).value
+	for run := range n.Runs() {
+		row := result[run.Y-coords.Min.Y]
+		for x := run.X; x < run.X+run.Len; x++ {
+			row[x-coords.Min.X] = 1
 		}
 	}
 	return result
 }
 
+// Width returns the width of the node. It overflows an int at MaxLevel, so
+// code which may see the root uses halfWidth instead.
 func (n *Node) Width() int {
 	return 1 << n.level
 }
+
+// halfWidth returns the distance from the center of the node to its edges.
+func (n *Node) halfWidth() int {
+	return 1 << (n.level - 1)
+}
@@ -2,43 +2,44 @@ package quadtree
 
 import (
 	"image"
-	"math/big"
 	"math/rand"
+	"slices"
 	"testing"
 	"time"
 
+	"gabe565.com/cli-of-life/internal/rule"
 	"github.com/stretchr/testify/assert"
 )
 
-func treeWithRandomPattern(level uint) (*Node, *big.Int) {
-	node := Empty(1)
-	for range level {
-		node = node.grow()
+// mustNode returns n, panicking if err is not nil.
+func mustNode(n *Node, err error) *Node {
+	if err != nil {
+		panic(err)
 	}
-	edgeLength := int(1) << level
-	cellsInTree := uint(edgeLength * edgeLength)
+	return n
+}
 
-	upperBound := new(big.Int)
-	upperBound.SetInt64(1)
-	upperBound.Lsh(upperBound, cellsInTree-1)
+// treeWithRandomPattern returns a tree of the given level filled with random
+// cells, along with the expected value of every cell.
+func treeWithRandomPattern(level uint) (*Node, map[image.Point]int) {
+	node := Empty(uint8(level)) //nolint:gosec
 	r := rand.New(rand.NewSource(time.Now().UnixNano()))
-	randomNumber := new(big.Int).Rand(r, upperBound)
-
-	for x := range edgeLength {
-		for y := range edgeLength {
-			bitPosition := x*edgeLength + y
-			if randomNumber.Bit(bitPosition) != 0 {
-				p := image.Pt(x-edgeLength/2, y-edgeLength/2)
+	cells := make(map[image.Point]int)
+	w := node.Width() / 2
+	for y := -w; y < w; y++ {
+		for x := -w; x < w; x++ {
+			if r.Intn(2) == 1 {
+				p := image.Pt(x, y)
 				node = node.Set(p, 1)
+				cells[p] = 1
 			}
 		}
 	}
-
-	return node, randomNumber
+	return node, cells
 }
 
 func treeCorrectness(t *testing.T, node *Node) {
-	if node.level == 0 {
+	if node.level == LeafLevel {
 		for _, child := range node.children() {
 			assert.Nil(t, child, "Leaf nodes shouldn't have child nodes")
 		}
@@ -52,22 +53,54 @@ func treeCorrectness(t *testing.T, node *Node) {
 	}
 }
 
-// slashLevelOne returns a level one tree with the following pattern:
-//
-//	0 | 1
-//	1 | 0
-func slashLevelOne() *Node {
-	return Empty(1).
-		Set(image.Pt(0, -1), 1).
-		Set(image.Pt(-1, 0), 1)
+// assertRegion asserts that every cell of got matches the cells of the region
+// of the same size centered at center.
+func assertRegion(t *testing.T, cells map[image.Point]int, got *Node, center image.Point) {
+	t.Helper()
+	w := got.Width() / 2
+	for y := -w; y < w; y++ {
+		for x := -w; x < w; x++ {
+			p := image.Pt(x, y)
+			if !assert.Equal(t, cells[p.Add(center)], got.Cell(p), "%s", p) {
+				return
+			}
+		}
+	}
 }
 
-// backslashLevelOne returns a level one tree with the following pattern:
-//
-//	1 | 0
-//	0 | 1
-func backslashLevelOne() *Node {
-	return Empty(1).
-		Set(image.Pt(0, 0), 1).
-		Set(image.Pt(-1, -1), 1)
+func neighbors(p image.Point, alive func(image.Point) bool) int {
+	var count int
+	for y := -1; y <= 1; y++ {
+		for x := -1; x <= 1; x++ {
+			if (x != 0 || y != 0) && alive(p.Add(image.Pt(x, y))) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func nextState(alive bool, neighbors int, r *rule.Rule) bool {
+	if alive {
+		return slices.Contains(r.Survive, neighbors)
+	}
+	return slices.Contains(r.Born, neighbors)
+}
+
+// assertNextGeneration asserts that got is the center of the pattern after one
+// generation, computed cell by cell.
+func assertNextGeneration(t *testing.T, cells map[image.Point]int, got *Node, r *rule.Rule) {
+	t.Helper()
+	want := make(map[image.Point]int)
+	w := got.Width() / 2
+	for y := -w; y < w; y++ {
+		for x := -w; x < w; x++ {
+			p := image.Pt(x, y)
+			n := neighbors(p, func(p image.Point) bool { return cells[p] != 0 })
+			if nextState(cells[p] != 0, n, r) {
+				want[p] = 1
+			}
+		}
+	}
+	assertRegion(t, want, got, image.Point{})
 }
@@ -8,18 +8,19 @@ import (
 
 	"gabe565.com/cli-of-life/internal/rule"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEmpty(t *testing.T) {
 	t.Run("level 0", func(t *testing.T) {
 		node := Empty(0)
-		assert.EqualValues(t, 0, node.level)
+		assert.EqualValues(t, LeafLevel, node.level)
 	})
 
 	t.Run("level -1", func(t *testing.T) {
 		node := Empty(0)
 		node = Empty(node.level - 1)
-		assert.EqualValues(t, 0, node.level)
+		assert.EqualValues(t, LeafLevel, node.level)
 	})
 
 	t.Run("level 7 correctness", func(t *testing.T) {
@@ -28,232 +29,202 @@ func TestEmpty(t *testing.T) {
 }
 
 func TestNode_GrowToFit(t *testing.T) {
-	node := Empty(1).
-		GrowToFit(image.Pt(63, 63))
+	node, err := Empty(1).GrowToFit(image.Pt(63, 63))
+	require.NoError(t, err)
 	assert.EqualValues(t, 7, node.level)
 	treeCorrectness(t, node)
+
+	t.Run("overflow", func(t *testing.T) {
+		_, err := Empty(1).GrowToFit(image.Pt(1<<62, 0))
+		require.ErrorIs(t, err, ErrUniverseOverflow)
+	})
+}
+
+func TestNode_grow(t *testing.T) {
+	node := Empty(MaxLevel - 1)
+	node, err := node.grow()
+	require.NoError(t, err)
+	assert.EqualValues(t, MaxLevel, node.level)
+
+	_, err = node.grow()
+	require.ErrorIs(t, err, ErrUniverseOverflow)
+}
+
+func TestNode_shrink(t *testing.T) {
+	cells := []image.Point{{1<<61 + 5, -1<<61 - 3}, {1<<61 + 6, -1<<61 - 3}}
+	node := Empty(MaxLevel)
+	for _, p := range cells {
+		node = node.Set(p, 1)
+	}
+
+	shrunk, shift := node.shrink(DefaultLevel)
+	assert.EqualValues(t, DefaultLevel, shrunk.level)
+	assert.Equal(t, node.value, shrunk.value)
+	for _, p := range cells {
+		assert.Equal(t, 1, shrunk.Cell(p.Sub(shift)))
+	}
+
+	t.Run("spread out", func(t *testing.T) {
+		node := Empty(MaxLevel).Set(image.Pt(-1<<62, -1<<62), 1).Set(image.Pt(1<<62-1, 1<<62-1), 1)
+		shrunk, shift := node.shrink(DefaultLevel)
+		assert.Same(t, node, shrunk)
+		assert.Equal(t, image.Point{}, shift)
+	})
 }
 
 func TestNode_Set(t *testing.T) {
 	t.Run("panics", func(t *testing.T) {
-		node := Empty(1).GrowToFit(image.Pt(3, 3))
+		node := mustNode(Empty(1).GrowToFit(image.Pt(3, 3)))
 		assert.Panics(t, func() {
 			node = node.Set(image.Pt(8, 8), 1)
 		})
 	})
 
+	t.Run("shares leaves", func(t *testing.T) {
+		a := Empty(5).Set(image.Pt(-9, -9), 1)
+		b := Empty(5).Set(image.Pt(15, 15), 1)
+		assert.Same(t, a.NW.NW, b.SE.SE)
+	})
+
 	t.Run("succeeds", func(t *testing.T) {
 		node := Empty(1)
 		for i := range 10 {
 			x, y := i-5*3, i-5*i
-			node = node.GrowToFit(image.Pt(x, y)).Set(image.Pt(x, y), 1)
-			assert.Equal(t, 1, node.Get(image.Pt(x, y), 0).value)
+			node = mustNode(node.GrowToFit(image.Pt(x, y))).Set(image.Pt(x, y), 1)
+			assert.Equal(t, 1, node.Cell(image.Pt(x, y)))
 			node = node.Set(image.Pt(x, y), 0)
-			assert.Equal(t, 0, node.Get(image.Pt(x, y), 0).value)
+			assert.Equal(t, 0, node.Cell(image.Pt(x, y)))
 		}
 
 		// check that not all cells get set
 		node = node.Set(image.Pt(1, 1), 1)
-		assert.Equal(t, 0, node.Get(image.Pt(2, 2), 0).value)
+		assert.Equal(t, 0, node.Cell(image.Pt(2, 2)))
 	})
 }
 
 func TestNode_Get(t *testing.T) {
-	node := Empty(1).GrowToFit(image.Pt(55, 233))
-	assert.Equal(t, 0, node.Get(image.Pt(55, 233), 0).value)
+	node := mustNode(Empty(1).GrowToFit(image.Pt(55, 233)))
+	assert.Equal(t, 0, node.Cell(image.Pt(55, 233)))
 	node = node.Set(image.Pt(55, 233), 1)
-	assert.Equal(t, 1, node.Get(image.Pt(55, 233), 0).value)
+	assert.Equal(t, 1, node.Cell(image.Pt(55, 233)))
 	treeCorrectness(t, node)
 }
 
 func TestNode_Visit(t *testing.T) {
-	node := Empty(1).
-		GrowToFit(image.Pt(55, 233)).
+	node := mustNode(Empty(1).GrowToFit(image.Pt(55, 233))).
 		Set(image.Pt(55, 232), 1).
 		Set(image.Pt(55, 233), 1)
-	var callCount int
-	node.Visit(func(p image.Point, node *Node) {
-		switch callCount {
-		case 0:
-			assert.Equal(t, image.Pt(55, 233), p)
-		case 1:
-			assert.Equal(t, image.Pt(55, 232), p)
-		}
-		assert.EqualValues(t, 0, node.level, "level")
-		assert.Equal(t, 1, node.value, "value")
-		callCount++
+	var got []image.Point
+	node.Visit(func(p image.Point) {
+		got = append(got, p)
 	})
-	assert.Equal(t, 2, callCount)
+	assert.Equal(t, []image.Point{{55, 232}, {55, 233}}, got)
 }
 
-func Test_oneGen(t *testing.T) {
-	r := rule.GameOfLife()
-
-	t.Run("dying", func(t *testing.T) {
-		assert.Equal(t, 0, oneGen(0xFFFF, &r).value)
-	})
-
-	t.Run("none alive", func(t *testing.T) {
-		assert.Equal(t, 0, oneGen(0x0000, &r).value)
-	})
-
-	t.Run("live neighbors", func(t *testing.T) {
-		// 0b0111_0000_0000
-		assert.Equal(t, 1, oneGen(0x0700, &r).value)
-	})
-
-	t.Run("live neighbors and self is alive", func(t *testing.T) {
-		// 0b0011_0010_0000
-		assert.Equal(t, 1, oneGen(0x0320, &r).value)
-	})
-
-	t.Run("live neighbors and self is alive", func(t *testing.T) {
-		// 0b0010_0010_0000
-		assert.Equal(t, 0, oneGen(0x0220, &r).value)
-	})
-
-	t.Run("live neighbors below", func(t *testing.T) {
-		// 0b0000_0000_0111
-		assert.Equal(t, 1, oneGen(0x0007, &r).value)
-	})
+func TestNode_Cell(t *testing.T) {
+	node, cells := treeWithRandomPattern(5)
+	w := node.Width() / 2
+	for y := -w; y < w; y++ {
+		for x := -w; x < w; x++ {
+			p := image.Pt(x, y)
+			assert.Equal(t, cells[p], node.Cell(p), p)
+		}
+	}
 }
 
-func TestNode_centeredSubnode(t *testing.T) {
-	node := Empty(3).
-		Set(image.Pt(1, 1), 1).
-		Set(image.Pt(-1, -1), 1)
-	center := node.centeredSubnode().grow()
-	assert.Equal(t, node, center)
+func Test_newRuleTable(t *testing.T) {
+	for name, r := range map[string]rule.Rule{
+		"life":     rule.GameOfLife(),
+		"highlife": rule.HighLife(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			table := lookupRuleTable(&r)
+			for i := range table {
+				var want uint8
+				for j, c := range []image.Point{{1, 1}, {2, 1}, {1, 2}, {2, 2}} {
+					alive := i>>(c.Y*4+c.X)&1 != 0
+					if nextState(alive, neighbors(c, func(p image.Point) bool {
+						return p.X >= 0 && p.Y >= 0 && p.X < 4 && p.Y < 4 && i>>(p.Y*4+p.X)&1 != 0
+					}), &r) {
+						want |= 1 << j
+					}
+				}
+				if !assert.Equal(t, want, table[i], "%016b", i) {
+					return
+				}
+			}
+		})
+	}
 }
 
-func TestNode_centeredNHorizontal(t *testing.T) {
-	t.Run("backslash", func(t *testing.T) {
-		node := Empty(3).
-			Set(image.Pt(-1, -3), 1).
-			Set(image.Pt(0, -2), 1).
-			centeredNHorizontal()
-		assert.Equal(t, backslashLevelOne(), node)
-	})
-
-	t.Run("slash", func(t *testing.T) {
-		node := Empty(3).
-			Set(image.Pt(0, -3), 1).
-			Set(image.Pt(-1, -2), 1).
-			centeredNHorizontal()
-		assert.Equal(t, slashLevelOne(), node)
-	})
-}
+func Test_stepLeaves(t *testing.T) {
+	r := rule.GameOfLife()
 
-func TestNode_centeredSHorizontal(t *testing.T) {
-	t.Run("backslash", func(t *testing.T) {
-		node := Empty(3).
-			Set(image.Pt(-1, 1), 1).
-			Set(image.Pt(0, 2), 1).
-			centeredSHorizontal()
-		assert.Equal(t, backslashLevelOne(), node)
+	t.Run("empty stays empty", func(t *testing.T) {
+		assert.Same(t, emptyLeaf, stepLeaves(Empty(LeafLevel+1), lookupRuleTable(&r)))
 	})
 
-	t.Run("slash", func(t *testing.T) {
-		node := Empty(3).
-			Set(image.Pt(0, 1), 1).
-			Set(image.Pt(-1, 2), 1).
-			centeredSHorizontal()
-		assert.Equal(t, slashLevelOne(), node)
+	t.Run("matches naive simulation", func(t *testing.T) {
+		for range 20 {
+			node, cells := treeWithRandomPattern(LeafLevel + 1)
+			next := stepLeaves(node, lookupRuleTable(&r))
+			assertNextGeneration(t, cells, next, &r)
+		}
 	})
 }
 
-func TestNode_centeredWVertical(t *testing.T) {
-	t.Run("backslash", func(t *testing.T) {
-		node := Empty(3).
-			Set(image.Pt(-3, -1), 1).
-			Set(image.Pt(-2, 0), 1).
-			centeredWVertical()
-		assert.Equal(t, backslashLevelOne(), node)
-	})
+func TestNode_centeredSubnode(t *testing.T) {
+	for _, level := range []uint{LeafLevel + 1, LeafLevel + 2} {
+		node, cells := treeWithRandomPattern(level)
+		assertRegion(t, cells, node.centeredSubnode(), image.Point{})
+	}
 
-	t.Run("slash", func(t *testing.T) {
-		node := Empty(3).
-			Set(image.Pt(-2, -1), 1).
-			Set(image.Pt(-3, 0), 1).
-			centeredWVertical()
-		assert.Equal(t, slashLevelOne(), node)
+	t.Run("grow", func(t *testing.T) {
+		node := Empty(5).
+			Set(image.Pt(1, 1), 1).
+			Set(image.Pt(-1, -1), 1)
+		assert.Equal(t, node, mustNode(node.centeredSubnode().grow()))
 	})
 }
 
-func TestNode_centeredEVertical(t *testing.T) {
-	t.Run("backslash", func(t *testing.T) {
-		node := Empty(3).
-			Set(image.Pt(1, -1), 1).
-			Set(image.Pt(2, 0), 1).
-			centeredEVertical()
-		assert.Equal(t, backslashLevelOne(), node)
-	})
+func TestNode_centeredHorizontal(t *testing.T) {
+	for _, level := range []uint{LeafLevel + 2, LeafLevel + 3} {
+		node, cells := treeWithRandomPattern(level)
+		q := node.Width() / 4
+		assertRegion(t, cells, node.centeredNHorizontal(), image.Pt(0, -q))
+		assertRegion(t, cells, node.centeredSHorizontal(), image.Pt(0, q))
+	}
+}
 
-	t.Run("slash", func(t *testing.T) {
-		node := Empty(3).
-			Set(image.Pt(2, -1), 1).
-			Set(image.Pt(1, 0), 1).
-			centeredEVertical()
-		assert.Equal(t, slashLevelOne(), node)
-	})
+func TestNode_centeredVertical(t *testing.T) {
+	for _, level := range []uint{LeafLevel + 2, LeafLevel + 3} {
+		node, cells := treeWithRandomPattern(level)
+		q := node.Width() / 4
+		assertRegion(t, cells, node.centeredWVertical(), image.Pt(-q, 0))
+		assertRegion(t, cells, node.centeredEVertical(), image.Pt(q, 0))
+	}
 }
 
 func TestNode_centeredSubSubnode(t *testing.T) {
-	node, _ := treeWithRandomPattern(1)
-	centeredSubSubnode := node.grow().grow().centeredSubSubnode()
-	assert.Equal(t, node, centeredSubSubnode)
+	for _, level := range []uint{LeafLevel + 2, LeafLevel + 3} {
+		node, cells := treeWithRandomPattern(level)
+		assertRegion(t, cells, node.centeredSubSubnode(), image.Point{})
+	}
 }
 
-func TestNode_slowSimulation(t *testing.T) {
+func TestNode_step(t *testing.T) {
 	r := rule.GameOfLife()
-
-	t.Run("empty stays empty", func(t *testing.T) {
-		node := Empty(2).slowSimulation(&r)
-		assert.Equal(t, Empty(1), node)
-	})
-
-	// 1 | 1
-	// 0 | 1
-	t.Run("SW empty", func(t *testing.T) {
-		node := Empty(2).
-			Set(image.Pt(-1, -1), 1).
-			Set(image.Pt(0, -1), 1).
-			Set(image.Pt(0, 0), 1).
-			slowSimulation(&r)
-
-		expect := Empty(1).
-			Set(image.Pt(0, 0), 1).
-			Set(image.Pt(-1, 0), 1).
-			Set(image.Pt(-1, -1), 1).
-			Set(image.Pt(0, -1), 1)
-		assert.Equal(t, expect, node)
-
-		// next generation should be full
-		node = node.grow().slowSimulation(&r)
-		assert.Equal(t, expect, node)
-	})
-
-	// 1 | 1| 1| 1
-	// 1 | 1| 1| 1
-	// 1 | 1| 1| 1
-	// 1 | 1| 1| 1
-	t.Run("full", func(t *testing.T) {
-		node := Empty(2)
-		for x := -2; x < 2; x++ {
-			for y := -2; y < 2; y++ {
-				node = node.Set(image.Pt(x, y), 1)
-			}
-		}
-		node = node.slowSimulation(&r)
-		assert.Equal(t, Empty(1), node)
-	})
+	for range 5 {
+		node, cells := treeWithRandomPattern(LeafLevel + 3)
+		assertNextGeneration(t, cells, node.step(&r), &r)
+	}
 }
 
 // trivial case of empty tree
 // more testing should happen on universe level.
 func TestNode_NextGeneration(t *testing.T) {
-	node := Empty(4).grow()
-	next := node.step(new(rule.GameOfLife())).grow()
+	node := mustNode(Empty(4).grow())
+	next := mustNode(node.step(new(rule.GameOfLife())).grow())
 	assert.Equal(t, node, next)
 	assert.NotNil(t, node.next)
 }
@@ -262,7 +233,7 @@ func TestNode_Width(t *testing.T) {
 	for i := range uint8(16) {
 		t.Run(strconv.Itoa(int(i)), func(t *testing.T) {
 			node := Empty(i)
-			expect := int(math.Pow(2, float64(i)))
+			expect := int(math.Pow(2, float64(max(i, LeafLevel))))
 			assert.Equal(t, expect, node.Width())
 		})
 	}
@@ -292,12 +263,33 @@ func TestNode_FilledCoords(t *testing.T) {
 				Set(image.Pt(2, 2), 1),
 			image.Rect(-2, -2, 3, 3),
 		},
+		{
+			"far apart",
+			mustNode(Empty(1).GrowToFit(image.Pt(-1<<40, 1<<40))).
+				Set(image.Pt(-1<<40, 5), 1).
+				Set(image.Pt(3, 1<<40), 1).
+				Set(image.Pt(0, 0), 1),
+			image.Rect(-1<<40, 0, 4, 1<<40+1),
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			assert.Equal(t, tt.want, tt.node.FilledCoords())
 		})
 	}
+
+	t.Run("random", func(t *testing.T) {
+		for range 20 {
+			node, cells := treeWithRandomPattern(LeafLevel + 3)
+			var want image.Rectangle
+			for p, v := range cells {
+				if v != 0 {
+					want = want.Union(image.Rect(p.X, p.Y, p.X+1, p.Y+1))
+				}
+			}
+			assert.Equal(t, want, node.FilledCoords())
+		}
+	})
 }
 
 func TestNode_ToSlice(t *testing.T) {
@@ -3,18 +3,22 @@ package quadtree
 import (
 	"bytes"
 	"image"
-	"slices"
-	"strconv"
+	"image/color"
 	"strings"
 
 	"charm.land/lipgloss/v2"
+	"gabe565.com/cli-of-life/internal/theme"
 )
 
 //nolint:gochecknoglobals
 var (
 	colors         []lipgloss.Style
+	palette        color.Palette
 	halfBlocks     [16]string
+	asciiBlocks    [16]string
+	ascii          bool
 	darkBackground = true
+	currentTheme   = theme.New()
 )
 
 func init() { //nolint:gochecknoinits
@@ -38,21 +42,45 @@ func init() { //nolint:gochecknoinits
 	for p := range halfBlocks {
 		halfBlocks[p] = half(p&1 != 0, p&4 != 0) + half(p&2 != 0, p&8 != 0)
 	}
+
+	// ASCII mode marks columns with both halves occupied with "#" and columns
+	// with one half occupied with "o".
+	halfASCII := func(top, bottom bool) string {
+		switch {
+		case top && bottom:
+			return "#"
+		case top || bottom:
+			return "o"
+		default:
+			return "."
+		}
+	}
+	for p := range asciiBlocks {
+		asciiBlocks[p] = halfASCII(p&1 != 0, p&4 != 0) + halfASCII(p&2 != 0, p&8 != 0)
+	}
 }
 
-// buildColors builds the density color gradient, ordering it to suit the
-// current background. On light backgrounds the gradient is reversed so that
-// cells stay legible.
+// buildColors builds the density color gradient from the current theme,
+// choosing its variant for the current background.
 func buildColors() {
-	const first, last = 236, 254
-	colors = make([]lipgloss.Style, 0, last-first+2)
-	for i := first; i <= last; i++ {
-		colors = append(colors, lipgloss.NewStyle().Foreground(lipgloss.Color(strconv.Itoa(i))))
-	}
-	if !darkBackground {
-		slices.Reverse(colors)
+	gradient := currentTheme.Cells(darkBackground)
+	colors = make([]lipgloss.Style, 0, len(gradient))
+	palette = make(color.Palette, 0, len(gradient)+1)
+	palette = append(palette, color.Transparent)
+	for _, c := range gradient {
+		style := lipgloss.NewStyle()
+		if _, ok := c.(lipgloss.NoColor); ok {
+			// Images have no foreground color, so use the opposite of the background.
+			c = color.White
+			if !darkBackground {
+				c = color.Black
+			}
+		} else {
+			style = style.Foreground(c)
+		}
+		colors = append(colors, style)
+		palette = append(palette, color.RGBAModel.Convert(c))
 	}
-	colors = append(colors, lipgloss.NewStyle())
 }
 
 func SetDarkBackground(dark bool) {
@@ -62,63 +90,122 @@ func SetDarkBackground(dark bool) {
 	}
 }
 
+// SetASCII enables rendering with only ASCII characters and no colors.
+func SetASCII(enabled bool) {
+	ascii = enabled
+}
+
+// SetTheme changes the colors used to shade cells.
+func SetTheme(t theme.Theme) {
+	currentTheme = t
+	buildColors()
+}
+
 type cell struct {
 	str   string
 	color int
 }
 
+// Render writes the cells within rect, with each character pair showing a
+// square of 2^level cells.
 func (n *Node) Render(buf *bytes.Buffer, rect image.Rectangle, level uint8) {
 	skip := 1 << level
+	size := image.Pt(
+		max(rect.Dx()+skip-1, 0)>>level,
+		max(rect.Dy()+skip-1, 0)>>level,
+	)
+	if size.Y == 0 {
+		return
+	}
+	v := newViewport(image.Pt(rect.Min.X>>level, rect.Min.Y>>level), size, level)
+	n.walk(v, &halfBlockWriter{buf: buf, level: level, size: size})
+}
+
+// halfBlockWriter writes two columns per block, using half-block glyphs to
+// show which quadrants of a block are occupied.
+type halfBlockWriter struct {
+	buf   *bytes.Buffer
+	level uint8
+	size  image.Point
+	row   int
+}
+
+func (w *halfBlockWriter) writeRow(row int, cells []blockCell) {
+	for w.row < row {
+		w.writeCells(nil)
+	}
+	w.writeCells(cells)
+}
+
+func (w *halfBlockWriter) finish() {
+	for w.row < w.size.Y {
+		w.writeCells(nil)
+	}
+}
+
+func (w *halfBlockWriter) writeCells(cells []blockCell) {
+	empty := renderCell(block{}, w.level)
+	var col int
 	var prev cell
 	var consecutive int
-	for y := rect.Min.Y; y < rect.Max.Y; y += skip {
-		for x := rect.Min.X; x < rect.Max.X; x += skip {
-			node := n.Get(image.Pt(x, y), level)
-			if node == nil {
-				node = deadLeaf
-			}
-			cur := renderCell(node, level)
-			if consecutive > 0 && cur == prev {
-				consecutive++
-			} else {
-				if consecutive > 0 {
-					printCells(buf, prev, consecutive)
-				}
-				prev, consecutive = cur, 1
+	flush := func() {
+		if consecutive > 0 {
+			printCells(w.buf, prev, consecutive)
+		}
+	}
+	for _, c := range cells {
+		if c.col > col {
+			if prev != empty {
+				flush()
+				prev, consecutive = empty, 0
 			}
+			consecutive += c.col - col
 		}
-		if consecutive > 0 {
-			printCells(buf, prev, consecutive)
-			consecutive = 0
+		cur := renderCell(c.block, w.level)
+		if consecutive > 0 && cur == prev {
+			consecutive++
+		} else {
+			flush()
+			prev, consecutive = cur, 1
+		}
+		col = c.col + 1
+	}
+	if col < w.size.X {
+		if prev != empty {
+			flush()
+			prev, consecutive = empty, 0
 		}
-		buf.WriteByte('\n')
+		consecutive += w.size.X - col
 	}
+	flush()
+	w.buf.WriteByte('\n')
+	w.row++
 }
 
-func renderCell(node *Node, level uint8) cell {
+func renderCell(b block, level uint8) cell {
 	switch {
-	case node.value == 0:
+	case b.value == 0 && ascii:
+		return cell{str: "..", color: -1}
+	case b.value == 0:
 		return cell{str: "  ", color: -1}
+	case ascii && level == 0:
+		return cell{str: "##", color: -1}
+	case ascii:
+		return cell{str: asciiBlocks[b.pattern], color: -1}
 	case level == 0:
 		return cell{str: "██", color: len(colors) - 1}
 	default:
-		var pattern int
-		if node.NW.value > 0 {
-			pattern |= 1
-		}
-		if node.NE.value > 0 {
-			pattern |= 2
-		}
-		if node.SW.value > 0 {
-			pattern |= 4
-		}
-		if node.SE.value > 0 {
-			pattern |= 8
-		}
-		c := node.value * (len(colors) - 1) / (1 << (level + 1))
-		c = min(c, len(colors)-1)
-		return cell{str: halfBlocks[pattern], color: c}
+		return cell{str: halfBlocks[b.pattern], color: shade(b.value, level)}
+	}
+}
+
+// shade returns the index into colors for a block based on its population.
+func shade(value int, level uint8) int {
+	if level == 0 {
+		return len(colors) - 1
 	}
+	c := value * (len(colors) - 1) / (1 << (level + 1))
+	return min(c, len(colors)-1)
 }
 
 func printCells(buf *bytes.Buffer, c cell, consecutive int) {
@@ -0,0 +1,97 @@
+package quadtree
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"gabe565.com/cli-of-life/internal/theme"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNode_Render(t *testing.T) {
+	glider := Empty(5).
+		Set(image.Pt(1, 0), 1).
+		Set(image.Pt(2, 1), 1).
+		Set(image.Pt(0, 2), 1).
+		Set(image.Pt(1, 2), 1).
+		Set(image.Pt(2, 2), 1)
+
+	tests := []struct {
+		name  string
+		node  *Node
+		rect  image.Rectangle
+		level uint8
+		want  string
+	}{
+		{"empty", Empty(5), image.Rect(0, 0, 2, 2), 0, "    \n    \n"},
+		{"glider", glider, image.Rect(-1, 0, 4, 3), 0, "    ██    \n      ██  \n  ██████  \n"},
+		{"glider level 1", glider, image.Rect(0, 0, 4, 4), 1, " ▀▄ \n▀▀▀ \n"},
+		{"outside tree", glider, image.Rect(100, 100, 102, 101), 0, "    \n"},
+		{"zero width", glider, image.Rect(0, 0, 0, 2), 0, "\n\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.node.Render(&buf, tt.rect, tt.level)
+			assert.Equal(t, tt.want, ansi.Strip(buf.String()))
+		})
+	}
+}
+
+func TestSetTheme(t *testing.T) {
+	t.Cleanup(func() {
+		SetTheme(theme.New())
+	})
+
+	hc, err := theme.Parse(theme.HighContrast)
+	require.NoError(t, err)
+	SetTheme(hc)
+	require.Len(t, colors, 1)
+	assert.Len(t, palette, 2)
+	assert.Equal(t, 0, shade(4, 1))
+
+	SetTheme(theme.New())
+	assert.Len(t, colors, 20)
+	r, g, b, _ := palette[1].RGBA()
+	assert.Equal(t, [3]uint32{48 * 0x101, 48 * 0x101, 48 * 0x101}, [3]uint32{r, g, b})
+}
+
+func TestNode_Render_ascii(t *testing.T) {
+	SetASCII(true)
+	t.Cleanup(func() {
+		SetASCII(false)
+	})
+
+	glider := Empty(5).
+		Set(image.Pt(1, 0), 1).
+		Set(image.Pt(2, 1), 1).
+		Set(image.Pt(0, 2), 1).
+		Set(image.Pt(1, 2), 1).
+		Set(image.Pt(2, 2), 1)
+
+	tests := []struct {
+		name  string
+		rect  image.Rectangle
+		level uint8
+		want  string
+	}{
+		{"level 0", image.Rect(-1, 0, 4, 3), 0, "....##....\n......##..\n..######..\n"},
+		{"level 1", image.Rect(0, 0, 4, 4), 1, ".oo.\nooo.\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			glider.Render(&buf, tt.rect, tt.level)
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+
+	t.Run("zoomed", func(t *testing.T) {
+		var buf bytes.Buffer
+		glider.RenderZoomed(&buf, image.Rect(0, 1, 3, 3), 2, true)
+		assert.Equal(t, "...|...|###|\n---+---+---+\n###|###|###|\n---+---+---+\n", buf.String())
+	})
+}
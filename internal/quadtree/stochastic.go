@@ -0,0 +1,187 @@
+package quadtree
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"math/rand/v2"
+	"slices"
+
+	"gabe565.com/cli-of-life/internal/rule"
+)
+
+// StochasticOptions configures the probabilistic direct-simulation engine.
+type StochasticOptions struct {
+	// BirthChance is the probability that a dead cell satisfying the birth
+	// condition comes alive.
+	BirthChance float64
+	// SurviveChance is the probability that a live cell satisfying the survival
+	// condition stays alive.
+	SurviveChance float64
+	// Noise is the probability that any cell within the pattern's bounding box
+	// is flipped after each generation.
+	Noise float64
+	// Seed seeds the random number generator. If zero, a random seed is chosen.
+	Seed uint64
+}
+
+func DefaultStochasticOptions() StochasticOptions {
+	return StochasticOptions{
+		BirthChance:   1,
+		SurviveChance: 1,
+	}
+}
+
+// Enabled reports whether the options differ from a deterministic simulation.
+func (o StochasticOptions) Enabled() bool {
+	return o.BirthChance < 1 || o.SurviveChance < 1 || o.Noise > 0
+}
+
+var ErrInvalidProbability = errors.New("probability must be between 0 and 1")
+
+func (o StochasticOptions) Validate() error {
+	for _, v := range []struct {
+		name  string
+		value float64
+	}{
+		{"birth chance", o.BirthChance},
+		{"survive chance", o.SurviveChance},
+		{"noise", o.Noise},
+	} {
+		if v.value < 0 || v.value > 1 || math.IsNaN(v.value) {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidProbability, v.name, v.value)
+		}
+	}
+	return nil
+}
+
+// Stochastic steps a universe cell by cell, applying births and survivals with
+// a configurable probability. Because the result of a step is no longer a pure
+// function of the node, Hashlife memoization can not be used.
+type Stochastic struct {
+	opts StochasticOptions
+	rng  *rand.Rand
+}
+
+func NewStochastic(opts StochasticOptions) *Stochastic {
+	if opts.Seed == 0 {
+		opts.Seed = rand.Uint64() //nolint:gosec
+	}
+	s := &Stochastic{opts: opts}
+	s.Reset()
+	return s
+}
+
+// Seed returns the seed used by the random number generator.
+func (s *Stochastic) Seed() uint64 {
+	return s.opts.Seed
+}
+
+// Reset reseeds the random number generator so that a run can be reproduced.
+func (s *Stochastic) Reset() {
+	s.rng = rand.New(rand.NewPCG(s.opts.Seed, s.opts.Seed)) //nolint:gosec
+}
+
+func (s *Stochastic) chance(p float64) bool {
+	return p >= 1 || (p > 0 && s.rng.Float64() < p)
+}
+
+func (s *Stochastic) step(n *Node, r *rule.Rule) (*Node, error) {
+	alive := make(map[image.Point]struct{}, n.value)
+	neighbors := make(map[image.Point]int, n.value*8)
+	n.Visit(func(p image.Point) {
+		alive[p] = struct{}{}
+		for y := -1; y <= 1; y++ {
+			for x := -1; x <= 1; x++ {
+				if x != 0 || y != 0 {
+					neighbors[p.Add(image.Pt(x, y))]++
+				}
+			}
+		}
+	})
+
+	// Map iteration order is random, so candidates are sorted to keep runs
+	// reproducible for a given seed.
+	candidates := make([]image.Point, 0, len(neighbors))
+	for p := range neighbors {
+		candidates = append(candidates, p)
+	}
+	slices.SortFunc(candidates, comparePoints)
+
+	next := Empty(n.level)
+	for _, p := range candidates {
+		count := neighbors[p]
+		var v int
+		if _, ok := alive[p]; ok {
+			if slices.Contains(r.Survive, count) && s.chance(s.opts.SurviveChance) {
+				v = 1
+			}
+		} else if slices.Contains(r.Born, count) && s.chance(s.opts.BirthChance) {
+			v = 1
+		}
+		if v != 0 {
+			var err error
+			if next, err = next.GrowToFit(p); err != nil {
+				return nil, err
+			}
+			next = next.Set(p, v)
+		}
+	}
+
+	if s.opts.Noise > 0 && !n.IsEmpty() {
+		return s.noise(next, n.FilledCoords())
+	}
+	return next, nil
+}
+
+// noise flips cells within the rectangle. Gaps between flipped cells are drawn
+// from a geometric distribution so that the cost is proportional to the number
+// of flips rather than the area.
+func (s *Stochastic) noise(n *Node, rect image.Rectangle) (*Node, error) {
+	var err error
+	if s.opts.Noise >= 1 {
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				if n, err = flip(n, image.Pt(x, y)); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return n, nil
+	}
+
+	width := rect.Dx()
+	area := width * rect.Dy()
+	logQ := math.Log1p(-s.opts.Noise)
+	for i := s.skip(logQ); i < area; i += 1 + s.skip(logQ) {
+		if n, err = flip(n, rect.Min.Add(image.Pt(i%width, i/width))); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+func (s *Stochastic) skip(logQ float64) int {
+	skip := math.Floor(math.Log(1-s.rng.Float64()) / logQ)
+	if skip > math.MaxInt32 {
+		return math.MaxInt32
+	}
+	return int(skip)
+}
+
+func flip(n *Node, p image.Point) (*Node, error) {
+	n, err := n.GrowToFit(p)
+	if err != nil {
+		return nil, err
+	}
+	return n.Set(p, 1-n.Cell(p)), nil
+}
+
+func comparePoints(a, b image.Point) int {
+	if c := cmp.Compare(a.Y, b.Y); c != 0 {
+		return c
+	}
+	return cmp.Compare(a.X, b.X)
+}
@@ -0,0 +1,70 @@
+package quadtree
+
+import (
+	"image"
+	"testing"
+
+	"gabe565.com/cli-of-life/internal/rule"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func gliderGosper(t *testing.T) *Gosper {
+	t.Helper()
+	g := New()
+	for _, p := range []image.Point{{1, 0}, {2, 1}, {0, 2}, {1, 2}, {2, 2}} {
+		require.NoError(t, g.Set(p, 1))
+	}
+	g.SetReset()
+	return g
+}
+
+func TestStochastic_deterministic(t *testing.T) {
+	r := rule.GameOfLife()
+
+	want := gliderGosper(t)
+	require.NoError(t, want.Step(t.Context(), &r, 8, nil))
+
+	got := gliderGosper(t)
+	got.SetStochastic(NewStochastic(DefaultStochasticOptions()))
+	require.NoError(t, got.Step(t.Context(), &r, 8, nil))
+
+	assert.Equal(t, want.ToSlice(), got.ToSlice())
+	assert.Equal(t, want.Stats().Generation, got.Stats().Generation)
+}
+
+func TestStochastic_seed(t *testing.T) {
+	r := rule.GameOfLife()
+	opts := StochasticOptions{BirthChance: 0.8, SurviveChance: 0.9, Noise: 0.05, Seed: 565}
+
+	a := gliderGosper(t)
+	a.SetStochastic(NewStochastic(opts))
+	require.NoError(t, a.Step(t.Context(), &r, 20, nil))
+
+	b := gliderGosper(t)
+	b.SetStochastic(NewStochastic(opts))
+	require.NoError(t, b.Step(t.Context(), &r, 20, nil))
+	assert.Equal(t, a.ToSlice(), b.ToSlice())
+
+	t.Run("reset reproduces run", func(t *testing.T) {
+		want := a.ToSlice()
+		a.Reset()
+		require.NoError(t, a.Step(t.Context(), &r, 20, nil))
+		assert.Equal(t, want, a.ToSlice())
+	})
+}
+
+func TestStochastic_chance(t *testing.T) {
+	r := rule.GameOfLife()
+
+	g := gliderGosper(t)
+	g.SetStochastic(NewStochastic(StochasticOptions{BirthChance: 0, SurviveChance: 1, Seed: 1}))
+	require.NoError(t, g.Step(t.Context(), &r, 1, nil))
+	assert.Equal(t, [][]int{{0, 1}, {1, 1}}, g.ToSlice())
+}
+
+func TestStochasticOptions_Enabled(t *testing.T) {
+	assert.False(t, DefaultStochasticOptions().Enabled())
+	assert.True(t, StochasticOptions{BirthChance: 1, SurviveChance: 0.5}.Enabled())
+	assert.True(t, StochasticOptions{BirthChance: 1, SurviveChance: 1, Noise: 0.1}.Enabled())
+}
@@ -0,0 +1,174 @@
+package quadtree
+
+import (
+	"image"
+	"math/bits"
+)
+
+// viewport walks the blocks of a tree which are visible on screen. Blocks are
+// squares of 2^level cells addressed in block units, while bounds holds the
+// area covered by the viewport in cells.
+type viewport struct {
+	level  uint8
+	min    image.Point
+	size   image.Point
+	bounds image.Rectangle
+	cells  []blockCell
+}
+
+func newViewport(origin, size image.Point, level uint8) *viewport {
+	skip := 1 << level
+	return &viewport{
+		level:  level,
+		min:    origin,
+		size:   size,
+		bounds: image.Rectangle{Min: origin.Mul(skip), Max: origin.Add(size).Mul(skip)},
+	}
+}
+
+// blockCell is a non-empty block and the column it appears in, relative to
+// the viewport.
+type blockCell struct {
+	col   int
+	block block
+}
+
+// rowWriter receives rows of non-empty blocks in ascending order. Rows without
+// any blocks are skipped, so writers must fill them in themselves.
+type rowWriter interface {
+	writeRow(row int, cells []blockCell)
+	finish()
+}
+
+// walk visits the tree in horizontal strips which only include non-empty nodes
+// that intersect the viewport, so empty space is skipped in bulk and the cost
+// depends on what is visible rather than the viewport size.
+func (n *Node) walk(v *viewport, out rowWriter) {
+	w := n.halfWidth()
+	origin := image.Pt(-w, -w)
+	if n.value != 0 && v.intersects(n, origin) {
+		v.strip(out, []stripNode{{node: n, x: origin.X}}, origin.Y)
+	}
+	out.finish()
+}
+
+func (v *viewport) intersects(n *Node, origin image.Point) bool {
+	// The far edge is inclusive, since the exclusive edge of the root
+	// overflows an int.
+	w := n.halfWidth()
+	last := origin.Add(image.Pt(w-1, w-1)).Add(image.Pt(w, w))
+	return origin.X < v.bounds.Max.X && last.X >= v.bounds.Min.X &&
+		origin.Y < v.bounds.Max.Y && last.Y >= v.bounds.Min.Y
+}
+
+// strip walks a strip of same-level nodes sorted by x whose top edge is at y.
+func (v *viewport) strip(out rowWriter, strip []stripNode, y int) {
+	level := strip[0].node.level
+	switch {
+	case level == LeafLevel && v.level < LeafLevel:
+		v.leafStrip(out, strip, y)
+		return
+	case level <= v.level:
+		v.cells = v.cells[:0]
+		for _, s := range strip {
+			n := s.node
+			b := block{value: n.value}
+			if n.level == LeafLevel {
+				b.pattern = quadrantPattern(leafQuadrants(n.bits))
+			} else {
+				b.pattern = quadrantPattern(n.NW.value, n.NE.value, n.SW.value, n.SE.value)
+			}
+			v.appendCell(s.x>>v.level, b)
+		}
+		if len(v.cells) != 0 {
+			out.writeRow(y>>v.level-v.min.Y, v.cells)
+		}
+		return
+	}
+
+	w := 1 << (level - 1)
+	top := make([]stripNode, 0, 2*len(strip))
+	bottom := make([]stripNode, 0, 2*len(strip))
+	for _, s := range strip {
+		top = v.appendStrip(top, s.node.NW, s.x, y)
+		top = v.appendStrip(top, s.node.NE, s.x+w, y)
+		bottom = v.appendStrip(bottom, s.node.SW, s.x, y+w)
+		bottom = v.appendStrip(bottom, s.node.SE, s.x+w, y+w)
+	}
+	if len(top) != 0 {
+		v.strip(out, top, y)
+	}
+	if len(bottom) != 0 {
+		v.strip(out, bottom, y+w)
+	}
+}
+
+func (v *viewport) appendStrip(strip []stripNode, n *Node, x, y int) []stripNode {
+	if n.value == 0 || !v.intersects(n, image.Pt(x, y)) {
+		return strip
+	}
+	return append(strip, stripNode{node: n, x: x})
+}
+
+// appendCell adds a block at the given absolute column if it is within the viewport.
+func (v *viewport) appendCell(col int, b block) {
+	col -= v.min.X
+	if col >= 0 && col < v.size.X {
+		v.cells = append(v.cells, blockCell{col: col, block: b})
+	}
+}
+
+// leafStrip walks blocks smaller than a leaf by reading them from each leaf's
+// bitboard.
+func (v *viewport) leafStrip(out rowWriter, strip []stripNode, y int) {
+	size := 1 << v.level
+	for by := 0; by < leafWidth; by += size {
+		row := (y+by)>>v.level - v.min.Y
+		if row < 0 || row >= v.size.Y {
+			continue
+		}
+		v.cells = v.cells[:0]
+		for _, s := range strip {
+			for bx := 0; bx < leafWidth; bx += size {
+				if b := leafBlock(s.node.bits, image.Pt(bx, by), size); b.value != 0 {
+					v.appendCell((s.x+bx)>>v.level, b)
+				}
+			}
+		}
+		if len(v.cells) != 0 {
+			out.writeRow(row, v.cells)
+		}
+	}
+}
+
+// block describes a square of cells.
+type block struct {
+	value int
+	// pattern has a bit set for each occupied quadrant: NW=1, NE=2, SW=4, SE=8.
+	pattern int
+}
+
+func quadrantPattern(nw, ne, sw, se int) int {
+	var pattern int
+	for i, v := range []int{nw, ne, sw, se} {
+		if v > 0 {
+			pattern |= 1 << i
+		}
+	}
+	return pattern
+}
+
+// leafBlock returns the size x size block of a leaf with its top-left corner at p.
+func leafBlock(b uint64, p image.Point, size int) block {
+	res := block{value: bits.OnesCount64(b & blockMask(p, size))}
+	if res.value != 0 && size > 1 {
+		half := size / 2
+		res.pattern = quadrantPattern(
+			bits.OnesCount64(b&blockMask(p, half)),
+			bits.OnesCount64(b&blockMask(p.Add(image.Pt(half, 0)), half)),
+			bits.OnesCount64(b&blockMask(p.Add(image.Pt(0, half)), half)),
+			bits.OnesCount64(b&blockMask(p.Add(image.Pt(half, half)), half)),
+		)
+	}
+	return res
+}
@@ -0,0 +1,123 @@
+package quadtree
+
+import (
+	"bytes"
+	"image"
+	"strings"
+)
+
+// RenderZoomed writes the cells within rect magnified so that each cell is
+// drawn as a block of 2*scale columns by scale rows. If grid is set and the
+// scale allows it, the last column and row of each block are drawn as grid
+// lines.
+func (n *Node) RenderZoomed(buf *bytes.Buffer, rect image.Rectangle, scale int, grid bool) {
+	if scale <= 1 {
+		n.Render(buf, rect, 0)
+		return
+	}
+	size := image.Pt(max(rect.Dx(), 0), max(rect.Dy(), 0))
+	if size.Y == 0 {
+		return
+	}
+	w := &zoomWriter{buf: buf, size: size, live: make([]bool, size.X), width: 2 * scale, height: scale, grid: grid}
+	if grid {
+		w.width--
+		w.height--
+	}
+	n.walk(newViewport(rect.Min, size, 0), w)
+}
+
+// zoomWriter repeats each cell across a block of characters.
+type zoomWriter struct {
+	buf    *bytes.Buffer
+	size   image.Point
+	live   []bool
+	width  int
+	height int
+	grid   bool
+	row    int
+}
+
+func (w *zoomWriter) writeRow(row int, cells []blockCell) {
+	for w.row < row {
+		w.writeCells(nil)
+	}
+	w.writeCells(cells)
+}
+
+func (w *zoomWriter) finish() {
+	for w.row < w.size.Y {
+		w.writeCells(nil)
+	}
+}
+
+func (w *zoomWriter) writeCells(cells []blockCell) {
+	clear(w.live)
+	for _, c := range cells {
+		w.live[c.col] = true
+	}
+	for range w.height {
+		if w.grid {
+			w.writeGridLine()
+		} else {
+			w.writeLine()
+		}
+		w.buf.WriteByte('\n')
+	}
+	if w.grid {
+		line := strings.Repeat(w.glyph("─", "-"), w.width) + w.glyph("┼", "+")
+		w.writeGrid(strings.Repeat(line, w.size.X))
+		w.buf.WriteByte('\n')
+	}
+	w.row++
+}
+
+// writeLine writes one line of blocks, merging neighboring blocks with the
+// same state.
+func (w *zoomWriter) writeLine() {
+	for x := 0; x < len(w.live); {
+		end := x + 1
+		for end < len(w.live) && w.live[end] == w.live[x] {
+			end++
+		}
+		printCells(w.buf, w.cell(w.live[x]), (end-x)*w.width)
+		x = end
+	}
+}
+
+// writeGridLine writes one line of blocks, each followed by a vertical grid line.
+func (w *zoomWriter) writeGridLine() {
+	for _, live := range w.live {
+		printCells(w.buf, w.cell(live), w.width)
+		w.writeGrid(w.glyph("│", "|"))
+	}
+}
+
+// cell returns a single column of a block.
+func (w *zoomWriter) cell(live bool) cell {
+	switch {
+	case live && ascii:
+		return cell{str: "#", color: -1}
+	case live:
+		return cell{str: "█", color: len(colors) - 1}
+	case ascii:
+		return cell{str: ".", color: -1}
+	default:
+		return cell{str: " ", color: -1}
+	}
+}
+
+func (w *zoomWriter) glyph(unicode, fallback string) string {
+	if ascii {
+		return fallback
+	}
+	return unicode
+}
+
+func (w *zoomWriter) writeGrid(s string) {
+	if ascii {
+		w.buf.WriteString(s)
+	} else {
+		w.buf.WriteString(colors[0].Render(s))
+	}
+}
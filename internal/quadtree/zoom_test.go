@@ -0,0 +1,68 @@
+package quadtree
+
+import (
+	"bytes"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNode_RenderZoomed(t *testing.T) {
+	glider := Empty(5).
+		Set(image.Pt(1, 0), 1).
+		Set(image.Pt(2, 1), 1).
+		Set(image.Pt(0, 2), 1).
+		Set(image.Pt(1, 2), 1).
+		Set(image.Pt(2, 2), 1)
+
+	tests := []struct {
+		name  string
+		node  *Node
+		rect  image.Rectangle
+		scale int
+		grid  bool
+		want  string
+	}{
+		{"scale 1", glider, image.Rect(0, 0, 3, 1), 1, false, "  ██  \n"},
+		{"scale 1 grid", glider, image.Rect(0, 0, 3, 1), 1, true, "  ██  \n"},
+		{"scale 2", glider, image.Rect(0, 0, 3, 2), 2, false, "    ████    \n    ████    \n        ████\n        ████\n"},
+		{
+			"scale 2 grid", glider, image.Rect(0, 1, 3, 3), 2, true,
+			"   │   │███│\n───┼───┼───┼\n███│███│███│\n───┼───┼───┼\n",
+		},
+		{"scale 3", glider, image.Rect(1, 2, 2, 3), 3, false, "██████\n██████\n██████\n"},
+		{"empty", Empty(5), image.Rect(0, 0, 2, 1), 2, false, "        \n        \n"},
+		{"zero height", glider, image.Rect(0, 0, 2, 0), 2, false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.node.RenderZoomed(&buf, tt.rect, tt.scale, tt.grid)
+			assert.Equal(t, tt.want, ansi.Strip(buf.String()))
+		})
+	}
+
+	t.Run("random", func(t *testing.T) {
+		node, cells := treeWithRandomPattern(LeafLevel + 2)
+		rect := image.Rect(-20, -18, 21, 17)
+		const scale = 3
+		var want strings.Builder
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			var line strings.Builder
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				if cells[image.Pt(x, y)] != 0 {
+					line.WriteString(strings.Repeat("█", 2*scale))
+				} else {
+					line.WriteString(strings.Repeat(" ", 2*scale))
+				}
+			}
+			want.WriteString(strings.Repeat(line.String()+"\n", scale))
+		}
+		var buf bytes.Buffer
+		node.RenderZoomed(&buf, rect, scale, false)
+		assert.Equal(t, want.String(), ansi.Strip(buf.String()))
+	})
+}
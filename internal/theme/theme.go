@@ -0,0 +1,205 @@
+package theme
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"slices"
+	"strconv"
+	"strings"
+
+	"charm.land/lipgloss/v2"
+)
+
+// Theme holds the colors used to draw the game and menu.
+type Theme struct {
+	Name string
+
+	// Dark and Light are the gradients used to shade cells by population on
+	// dark and light backgrounds, ordered from sparse to dense. The last color
+	// is also used for single cells. lipgloss.NoColor uses the terminal's
+	// foreground color.
+	Dark  []color.Color
+	Light []color.Color
+
+	ButtonDark    color.Color
+	ButtonLight   color.Color
+	SelectedDark  color.Color
+	SelectedLight color.Color
+}
+
+// Cells returns the cell gradient for the background.
+func (t Theme) Cells(dark bool) []color.Color {
+	if dark {
+		return t.Dark
+	}
+	return t.Light
+}
+
+// Button returns the button and selected button colors for the background.
+func (t Theme) Button(dark bool) (button, selected color.Color) {
+	if dark {
+		return t.ButtonDark, t.SelectedDark
+	}
+	return t.ButtonLight, t.SelectedLight
+}
+
+const (
+	Grey         = "grey"
+	Viridis      = "viridis"
+	Inferno      = "inferno"
+	Cividis      = "cividis"
+	HighContrast = "high-contrast"
+
+	Default = Grey
+)
+
+// gradientSteps is the number of shades that true color gradients are blended into.
+const gradientSteps = 19
+
+func themes() []Theme {
+	defaultButtons := func(t Theme) Theme {
+		t.ButtonDark, t.ButtonLight = lipgloss.Color("#4A4A4A"), lipgloss.Color("#DDDADA")
+		t.SelectedDark, t.SelectedLight = lipgloss.Color("#4A4ABB"), lipgloss.Color("#aaf")
+		return t
+	}
+
+	return []Theme{
+		defaultButtons(Theme{
+			Name:  Grey,
+			Dark:  append(ansiRange(236, 254), lipgloss.NoColor{}),
+			Light: append(reversed(ansiRange(236, 254)), lipgloss.NoColor{}),
+		}),
+		withGradient(Theme{
+			Name:          Viridis,
+			ButtonDark:    lipgloss.Color("#3b528b"),
+			ButtonLight:   lipgloss.Color("#b5de2b"),
+			SelectedDark:  lipgloss.Color("#21918c"),
+			SelectedLight: lipgloss.Color("#5ec962"),
+		}, "#440154", "#3b528b", "#21918c", "#5ec962", "#fde725"),
+		withGradient(Theme{
+			Name:          Inferno,
+			ButtonDark:    lipgloss.Color("#4a0c6b"),
+			ButtonLight:   lipgloss.Color("#f7d13d"),
+			SelectedDark:  lipgloss.Color("#a52c60"),
+			SelectedLight: lipgloss.Color("#fb9b06"),
+		}, "#1b0c41", "#4a0c6b", "#781c6d", "#a52c60", "#cf4446", "#ed6925", "#fb9b06", "#f7d13d", "#fcffa4"),
+		// Cividis is designed to be readable with color vision deficiencies.
+		withGradient(Theme{
+			Name:          Cividis,
+			ButtonDark:    lipgloss.Color("#35456c"),
+			ButtonLight:   lipgloss.Color("#c8b866"),
+			SelectedDark:  lipgloss.Color("#666970"),
+			SelectedLight: lipgloss.Color("#948e77"),
+		}, "#00224e", "#35456c", "#666970", "#948e77", "#c8b866", "#fee838"),
+		{
+			Name:          HighContrast,
+			Dark:          []color.Color{lipgloss.BrightWhite},
+			Light:         []color.Color{lipgloss.Black},
+			ButtonDark:    lipgloss.Color("#444444"),
+			ButtonLight:   lipgloss.Color("#BBBBBB"),
+			SelectedDark:  lipgloss.Blue,
+			SelectedLight: lipgloss.BrightCyan,
+		},
+	}
+}
+
+// New returns the default theme.
+func New() Theme {
+	return themes()[0]
+}
+
+// Names returns the names of the built-in themes.
+func Names() []string {
+	t := themes()
+	names := make([]string, 0, len(t))
+	for _, theme := range t {
+		names = append(names, theme.Name)
+	}
+	return names
+}
+
+var (
+	ErrUnknownTheme = errors.New("unknown theme")
+	ErrInvalidColor = errors.New("invalid color")
+)
+
+// Parse returns the built-in theme with the given name. Otherwise, name is
+// parsed as a comma-separated list of hex or ANSI 256 colors which are blended
+// into a gradient from sparse to dense. The gradient is reversed on light
+// backgrounds.
+func Parse(name string) (Theme, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = Default
+	}
+	for _, t := range themes() {
+		if strings.EqualFold(t.Name, name) {
+			return t, nil
+		}
+	}
+
+	if !strings.Contains(name, ",") && !strings.HasPrefix(name, "#") && !isNumber(name) {
+		return Theme{}, fmt.Errorf("%w: %q", ErrUnknownTheme, name)
+	}
+
+	stops := strings.Split(name, ",")
+	for i, s := range stops {
+		stops[i] = strings.TrimSpace(s)
+		if err := validateColor(stops[i]); err != nil {
+			return Theme{}, err
+		}
+	}
+	t := New()
+	t.Name = name
+	return withGradient(t, stops...), nil
+}
+
+// isNumber reports whether s looks like an ANSI color index, so that an
+// out-of-range index is reported as an invalid color rather than an unknown theme.
+func isNumber(s string) bool {
+	_, err := strconv.Atoi(s)
+	return err == nil
+}
+
+func validateColor(s string) error {
+	if hex, ok := strings.CutPrefix(s, "#"); ok {
+		if len(hex) == 3 || len(hex) == 6 {
+			if _, err := strconv.ParseUint(hex, 16, 32); err == nil {
+				return nil
+			}
+		}
+	} else if i, err := strconv.Atoi(s); err == nil && i >= 0 && i <= 255 {
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidColor, s)
+}
+
+// withGradient blends the stops into the dark gradient, and reverses it for the
+// light gradient.
+func withGradient(t Theme, stops ...string) Theme {
+	colors := make([]color.Color, 0, len(stops))
+	for _, s := range stops {
+		colors = append(colors, lipgloss.Color(s))
+	}
+	if len(colors) > 1 {
+		colors = lipgloss.Blend1D(gradientSteps, colors...)
+	}
+	t.Dark = colors
+	t.Light = reversed(colors)
+	return t
+}
+
+func ansiRange(first, last int) []color.Color {
+	colors := make([]color.Color, 0, last-first+1)
+	for i := first; i <= last; i++ {
+		colors = append(colors, lipgloss.Color(strconv.Itoa(i)))
+	}
+	return colors
+}
+
+func reversed(colors []color.Color) []color.Color {
+	colors = slices.Clone(colors)
+	slices.Reverse(colors)
+	return colors
+}
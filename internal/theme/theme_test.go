@@ -0,0 +1,79 @@
+package theme
+
+import (
+	"image/color"
+	"slices"
+	"testing"
+
+	"charm.land/lipgloss/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		wantName string
+		wantErr  require.ErrorAssertionFunc
+	}{
+		{"", Grey, require.NoError},
+		{"grey", Grey, require.NoError},
+		{"Viridis", Viridis, require.NoError},
+		{" high-contrast ", HighContrast, require.NoError},
+		{"#f00", "#f00", require.NoError},
+		{"42", "42", require.NoError},
+		{"#000080, 214,#ffffff", "#000080, 214,#ffffff", require.NoError},
+		{"rainbow", "", require.Error},
+		{"#f00,red", "", require.Error},
+		{"#ff00zz,#fff", "", require.Error},
+		{"0,256", "", require.Error},
+		{"256", "", func(t require.TestingT, err error, _ ...any) {
+			require.ErrorIs(t, err, ErrInvalidColor)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.name)
+			tt.wantErr(t, err)
+			assert.Equal(t, tt.wantName, got.Name)
+		})
+	}
+}
+
+func TestParse_custom(t *testing.T) {
+	got, err := Parse("#000080,#ffffff")
+	require.NoError(t, err)
+	require.Len(t, got.Dark, gradientSteps)
+	assert.Equal(t, rgba(lipgloss.Color("#000080")), rgba(got.Dark[0]))
+	assert.Equal(t, rgba(lipgloss.Color("#ffffff")), rgba(got.Dark[len(got.Dark)-1]))
+
+	light := slices.Clone(got.Light)
+	slices.Reverse(light)
+	assert.Equal(t, got.Dark, light)
+
+	button, selected := got.Button(true)
+	assert.Equal(t, New().ButtonDark, button)
+	assert.Equal(t, New().SelectedDark, selected)
+}
+
+func TestThemes(t *testing.T) {
+	names := Names()
+	assert.Equal(t, Default, names[0])
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			theme, err := Parse(name)
+			require.NoError(t, err)
+			assert.NotEmpty(t, theme.Cells(true))
+			assert.NotEmpty(t, theme.Cells(false))
+			for _, dark := range []bool{true, false} {
+				button, selected := theme.Button(dark)
+				assert.NotNil(t, button)
+				assert.NotNil(t, selected)
+			}
+		})
+	}
+}
+
+func rgba(c color.Color) color.RGBA {
+	return color.RGBAModel.Convert(c).(color.RGBA) //nolint:errcheck,forcetypeassert
+}